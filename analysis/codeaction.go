@@ -0,0 +1,389 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2target"
+	"oss.terrastruct.com/d2/lib/color"
+)
+
+type quickFix struct {
+	title     string
+	edits     []lsp.TextEdit
+	preferred bool
+}
+
+// quickFixes maps a diagnostic code to the fixes offered for it.
+var quickFixes = map[string]func(text string, diagnostic lsp.Diagnostic) []quickFix{
+	codeUnclosedMap:              closeMap,
+	codeUnclosedArray:            closeLine("]"),
+	codeUnclosedDoubleQuote:      closeLine(`"`),
+	codeUnclosedSingleQuote:      closeLine("'"),
+	codeUnclosedSubstitution:     closeSubstitution,
+	codeUnexpectedText:           quoteValue,
+	codeUnexpectedMapTermination: removeTermination,
+	codeInvalidStyleKeyword:      fixStyleKeyword,
+	codeUnknownShape:             suggestValue(d2target.Shapes),
+	codeInvalidColor:             suggestValue(color.NamedColors),
+	codeInvalidDirection:         suggestValue([]string{"up", "down", "right", "left"}),
+	codeInvalidBoolean:           fixBoolean,
+	codeOutOfRange:               clampValue,
+}
+
+// closeMap closes the map on a new line at the end of the document, indented like
+// the line that opens it.
+func closeMap(text string, diagnostic lsp.Diagnostic) []quickFix {
+	line := lineAt(text, diagnostic.Range.Start.Line)
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+	lines := strings.Split(text, "\n")
+	last := len(lines) - 1
+	edit := lsp.TextEdit{
+		Range:   emptyRange(lsp.Position{Line: last, Character: utf16Len(lines[last])}),
+		NewText: "\n" + indent + "}",
+	}
+	if strings.HasSuffix(text, "\n") {
+		edit.Range = emptyRange(lsp.Position{Line: last})
+		edit.NewText = indent + "}\n"
+	}
+
+	return []quickFix{{title: "Insert missing }", edits: []lsp.TextEdit{edit}, preferred: true}}
+}
+
+// closeLine terminates the string or array at the end of the line it starts on.
+func closeLine(terminator string) func(string, lsp.Diagnostic) []quickFix {
+	return func(text string, diagnostic lsp.Diagnostic) []quickFix {
+		line := lineAt(text, diagnostic.Range.Start.Line)
+		end := lsp.Position{
+			Line:      diagnostic.Range.Start.Line,
+			Character: utf16Len(strings.TrimRight(line, " \t\r")),
+		}
+		return []quickFix{{
+			title:     "Insert missing " + terminator,
+			edits:     []lsp.TextEdit{{Range: emptyRange(end), NewText: terminator}},
+			preferred: true,
+		}}
+	}
+}
+
+var variablePath = regexp.MustCompile(`^\$\{[\w.-]*`)
+
+// closeSubstitution terminates the substitution right after the variable name.
+func closeSubstitution(text string, diagnostic lsp.Diagnostic) []quickFix {
+	line := lineAt(text, diagnostic.Range.Start.Line)
+	start := byteIndex(line, diagnostic.Range.Start.Character)
+	name := variablePath.FindString(line[start:])
+	if name == "" {
+		return nil
+	}
+
+	end := lsp.Position{
+		Line:      diagnostic.Range.Start.Line,
+		Character: utf16Len(line[:start+len(name)]),
+	}
+	return []quickFix{{
+		title:     "Insert missing }",
+		edits:     []lsp.TextEdit{{Range: emptyRange(end), NewText: "}"}},
+		preferred: true,
+	}}
+}
+
+// quoteValue wraps the value holding special characters in double quotes.
+func quoteValue(text string, diagnostic lsp.Diagnostic) []quickFix {
+	line := lineAt(text, diagnostic.Range.Start.Line)
+	colon := strings.LastIndex(line[:byteIndex(line, diagnostic.Range.Start.Character)], ":")
+	if colon == -1 {
+		return nil
+	}
+	rest := line[colon+1:]
+	start := colon + 1 + len(rest) - len(strings.TrimLeft(rest, " \t"))
+	end := len(strings.TrimRight(line, " \t\r"))
+	if start >= end {
+		return nil
+	}
+
+	value := line[start:end]
+	return []quickFix{{
+		title: "Quote value",
+		edits: []lsp.TextEdit{{
+			Range:   lineRange(diagnostic.Range.Start.Line, line, start, end),
+			NewText: d2format.Format(d2ast.FlatDoubleQuotedString(value)),
+		}},
+		preferred: true,
+	}}
+}
+
+// removeTermination removes a } that doesn't close any map, along with the spaces
+// before it when it ends the line.
+func removeTermination(text string, diagnostic lsp.Diagnostic) []quickFix {
+	line := lineAt(text, diagnostic.Range.Start.Line)
+	start := byteIndex(line, diagnostic.Range.Start.Character)
+	end := start + len("}")
+	if strings.TrimSpace(line[end:]) == "" {
+		start = len(strings.TrimRight(line[:start], " \t"))
+	}
+
+	return []quickFix{{
+		title:     "Remove unmatched }",
+		edits:     []lsp.TextEdit{{Range: lineRange(diagnostic.Range.Start.Line, line, start, end)}},
+		preferred: true,
+	}}
+}
+
+// fixStyleKeyword inserts the colon missing after a style keyword, or suggests the
+// keywords closest to a misspelled one.
+func fixStyleKeyword(text string, diagnostic lsp.Diagnostic) []quickFix {
+	keyword := rangeText(text, diagnostic.Range)
+	if name, value, ok := strings.Cut(keyword, " "); ok {
+		if _, isKeyword := d2ast.StyleKeywords[name]; isKeyword {
+			return []quickFix{{
+				title: "Insert missing colon",
+				edits: []lsp.TextEdit{{
+					Range:   diagnostic.Range,
+					NewText: name + ": " + strings.TrimSpace(value),
+				}},
+				preferred: true,
+			}}
+		}
+	}
+
+	keywords := make([]string, 0, len(d2ast.StyleKeywords))
+	for k := range d2ast.StyleKeywords {
+		keywords = append(keywords, k)
+	}
+	return replacements(diagnostic.Range, closest(keyword, keywords))
+}
+
+// suggestValue suggests the candidates closest to a misspelled value.
+func suggestValue(candidates []string) func(string, lsp.Diagnostic) []quickFix {
+	return func(text string, diagnostic lsp.Diagnostic) []quickFix {
+		value := strings.ToLower(rangeText(text, diagnostic.Range))
+		return replacements(diagnostic.Range, closest(value, candidates))
+	}
+}
+
+// fixBoolean offers both booleans, preferring the one the value most likely meant.
+func fixBoolean(text string, diagnostic lsp.Diagnostic) []quickFix {
+	preferred := ""
+	switch strings.ToLower(rangeText(text, diagnostic.Range)) {
+	case "yes", "y", "on", "1":
+		preferred = "true"
+	case "no", "n", "off", "0":
+		preferred = "false"
+	}
+
+	fixes := []quickFix{}
+	for _, value := range []string{"true", "false"} {
+		fixes = append(fixes, quickFix{
+			title:     fmt.Sprintf("Change to %s", value),
+			edits:     []lsp.TextEdit{{Range: diagnostic.Range, NewText: value}},
+			preferred: value == preferred,
+		})
+	}
+	return fixes
+}
+
+var numberBounds = regexp.MustCompile(`between (\S+) and (\S+)`)
+
+// clampValue replaces a number outside of the allowed range with the bound it
+// crossed.
+func clampValue(text string, diagnostic lsp.Diagnostic) []quickFix {
+	bounds := numberBounds.FindStringSubmatch(diagnostic.Message)
+	if bounds == nil {
+		return nil
+	}
+	value, err := strconv.ParseFloat(rangeText(text, diagnostic.Range), 64)
+	if err != nil {
+		return nil
+	}
+	lower, err := strconv.ParseFloat(bounds[1], 64)
+	if err != nil {
+		return nil
+	}
+	upper, err := strconv.ParseFloat(bounds[2], 64)
+	if err != nil {
+		return nil
+	}
+
+	bound := ""
+	switch {
+	case value < lower:
+		bound = bounds[1]
+	case value > upper:
+		bound = bounds[2]
+	default:
+		return nil
+	}
+	return []quickFix{{
+		title:     fmt.Sprintf("Clamp to %s", bound),
+		edits:     []lsp.TextEdit{{Range: diagnostic.Range, NewText: bound}},
+		preferred: true,
+	}}
+}
+
+func replacements(r lsp.Range, candidates []string) []quickFix {
+	fixes := []quickFix{}
+	for i, candidate := range candidates {
+		fixes = append(fixes, quickFix{
+			title:     fmt.Sprintf("Change to %q", candidate),
+			edits:     []lsp.TextEdit{{Range: r, NewText: candidate}},
+			preferred: i == 0,
+		})
+	}
+	return fixes
+}
+
+// closest returns up to three candidates within a couple of edits of value, closest
+// first.
+func closest(value string, candidates []string) []string {
+	type match struct {
+		candidate string
+		distance  int
+	}
+	matches := []match{}
+	for _, candidate := range candidates {
+		distance := editDistance(value, candidate)
+		if distance > 0 && distance <= 2 && distance < len(value) {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	slices.SortFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.candidate, b.candidate)
+	})
+
+	names := []string{}
+	for _, m := range matches[:min(len(matches), 3)] {
+		names = append(names, m.candidate)
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// rangeText returns the text r covers on its first line.
+func rangeText(text string, r lsp.Range) string {
+	line := lineAt(text, r.Start.Line)
+	start := byteIndex(line, r.Start.Character)
+	end := len(line)
+	if r.End.Line == r.Start.Line {
+		end = byteIndex(line, r.End.Character)
+	}
+	return line[start:end]
+}
+
+// lineRange returns the range between the byte indexes start and end of line.
+func lineRange(i int, line string, start, end int) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: i, Character: utf16Len(line[:start])},
+		End:   lsp.Position{Line: i, Character: utf16Len(line[:end])},
+	}
+}
+
+func emptyRange(position lsp.Position) lsp.Range {
+	return lsp.Range{Start: position, End: position}
+}
+
+// kindRequested reports whether actions of kind were asked for, only "refactor"
+// also asks for "refactor.extract".
+func kindRequested(only []lsp.CodeActionKind, kind lsp.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, o := range only {
+		if kind == o || strings.HasPrefix(string(kind), string(o)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) CodeActions(id any, uri lsp.DocumentURI, rng lsp.Range, context lsp.CodeActionContext) lsp.CodeActionResponse {
+	response := lsp.CodeActionResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.CodeAction{},
+	}
+	if _, ok := s.Documents[uri]; !ok {
+		return response
+	}
+
+	if kindRequested(context.Only, lsp.RefactorExtract) {
+		if action := s.extractContainer(uri, rng.Start); action != nil {
+			response.Result = append(response.Result, *action)
+		}
+		if action := s.extractClass(uri, rng); action != nil {
+			response.Result = append(response.Result, *action)
+		}
+		if action := s.extractVariable(uri, rng); action != nil {
+			response.Result = append(response.Result, *action)
+		}
+	}
+	if kindRequested(context.Only, lsp.RefactorInline) {
+		if action := s.inlineImport(uri, rng.Start); action != nil {
+			response.Result = append(response.Result, *action)
+		}
+	}
+	if kindRequested(context.Only, lsp.RefactorRewrite) {
+		response.Result = append(response.Result, s.rewriteKeyActions(uri, rng.Start)...)
+	}
+	if kindRequested(context.Only, lsp.RefactorMove) {
+		response.Result = append(response.Result, s.moveActions(uri, rng.Start)...)
+	}
+	if kindRequested(context.Only, lsp.Refactor) {
+		if action := s.deleteAction(uri, rng.Start); action != nil {
+			response.Result = append(response.Result, *action)
+		}
+	}
+	if !kindRequested(context.Only, lsp.QuickFix) {
+		return response
+	}
+
+	text := s.Documents[uri].Text
+	for _, diagnostic := range context.Diagnostics {
+		fix, ok := quickFixes[diagnostic.Code]
+		if !ok {
+			continue
+		}
+		for _, f := range fix(text, diagnostic) {
+			response.Result = append(response.Result, lsp.CodeAction{
+				Title:       f.title,
+				Kind:        lsp.QuickFix,
+				Diagnostics: []lsp.Diagnostic{diagnostic},
+				IsPreferred: f.preferred,
+				Edit: &lsp.WorkspaceEdit{
+					Changes: map[lsp.DocumentURI][]lsp.TextEdit{uri: f.edits},
+				},
+			})
+		}
+	}
+
+	return response
+}
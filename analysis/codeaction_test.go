@@ -0,0 +1,155 @@
+package analysis_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+// applyEdits applies edits that don't overlap to text, assuming ASCII text.
+func applyEdits(text string, edits []lsp.TextEdit) string {
+	offset := func(position lsp.Position) int {
+		lines := strings.SplitAfter(text, "\n")
+		n := 0
+		for _, line := range lines[:position.Line] {
+			n += len(line)
+		}
+		return n + position.Character
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		start, end := offset(edits[i].Range.Start), offset(edits[i].Range.End)
+		text = text[:start] + edits[i].NewText + text[end:]
+	}
+	return text
+}
+
+func TestCodeActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		titles []string
+		want   string
+	}{
+		{
+			name:   "unclosed map",
+			text:   "a: {\n  b: {\n    c\n",
+			titles: []string{"Insert missing }"},
+			want:   "a: {\n  b: {\n    c\n  }\n",
+		},
+		{
+			name:   "unclosed string",
+			text:   "a: \"hello  \n",
+			titles: []string{`Insert missing "`},
+			want:   "a: \"hello\"  \n",
+		},
+		{
+			name:   "unclosed array",
+			text:   "a.class: [x; y\n",
+			titles: []string{"Insert missing ]"},
+			want:   "a.class: [x; y]\n",
+		},
+		{
+			name:   "unclosed substitution",
+			text:   "vars: {x: 1}\na: ${x\n",
+			titles: []string{"Insert missing }"},
+			want:   "vars: {x: 1}\na: ${x}\n",
+		},
+		{
+			name:   "special characters",
+			text:   "a: x]y\n",
+			titles: []string{"Quote value"},
+			want:   "a: \"x]y\"\n",
+		},
+		{
+			name:   "unmatched map termination",
+			text:   "a: {b: c}}\n",
+			titles: []string{"Remove unmatched }"},
+			want:   "a: {b: c}\n",
+		},
+		{
+			name:   "missing colon",
+			text:   "a.style.fill red\n",
+			titles: []string{"Insert missing colon"},
+			want:   "a.style.fill: red\n",
+		},
+		{
+			name:   "misspelled style keyword",
+			text:   "a.style.fil: red\n",
+			titles: []string{`Change to "fill"`},
+			want:   "a.style.fill: red\n",
+		},
+		{
+			name:   "misspelled shape",
+			text:   "a.shape: rectangl\n",
+			titles: []string{`Change to "rectangle"`},
+			want:   "a.shape: rectangle\n",
+		},
+		{
+			name:   "misspelled color",
+			text:   "a.style.fill: gren\n",
+			titles: []string{`Change to "green"`, `Change to "grey"`, `Change to "gray"`},
+			want:   "a.style.fill: green\n",
+		},
+		{
+			name:   "misspelled direction",
+			text:   "direction: upp\n",
+			titles: []string{`Change to "up"`},
+			want:   "direction: up\n",
+		},
+		{
+			name:   "boolean",
+			text:   "a.style.bold: yes\n",
+			titles: []string{"Change to true", "Change to false"},
+			want:   "a.style.bold: true\n",
+		},
+		{
+			name:   "out of range",
+			text:   "a.style.opacity: 1.5\n",
+			titles: []string{"Clamp to 1.0"},
+			want:   "a.style.opacity: 1.0\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			uri := lsp.File(t.TempDir() + "/test.d2")
+			diagnostics := state.OpenDocument(uri, 1, test.text)
+			if len(diagnostics) == 0 {
+				t.Fatal("OpenDocument() returned no diagnostics")
+			}
+
+			context := lsp.CodeActionContext{
+				Diagnostics: diagnostics[:1],
+				Only:        []lsp.CodeActionKind{lsp.QuickFix},
+			}
+			response := state.CodeActions(1, uri, lsp.Range{}, context)
+			titles := []string{}
+			got := test.text
+			for _, action := range response.Result {
+				titles = append(titles, action.Title)
+				if action.IsPreferred {
+					got = applyEdits(test.text, action.Edit.Changes[uri])
+				}
+			}
+			if diff := cmp.Diff(test.titles, titles); diff != "" {
+				t.Errorf("CodeActions() titles mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("preferred fix mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCodeActionsClosedDocument(t *testing.T) {
+	state := newTestState(t)
+	uri := lsp.File(t.TempDir() + "/test.d2")
+
+	response := state.CodeActions(1, uri, lsp.Range{}, lsp.CodeActionContext{})
+	if diff := cmp.Diff([]lsp.CodeAction{}, response.Result); diff != "" {
+		t.Errorf("CodeActions() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,80 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+// commandArguments decodes the arguments of a command into targets, one per
+// argument.
+func commandArguments(arguments []json.RawMessage, targets ...any) error {
+	if len(arguments) != len(targets) {
+		return fmt.Errorf("expected %d arguments, got %d", len(targets), len(arguments))
+	}
+	for i, argument := range arguments {
+		if err := json.Unmarshal(argument, targets[i]); err != nil {
+			return fmt.Errorf("argument %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// ExecuteCommand runs command. Commands that change documents return the edit for
+// the client to apply.
+func (s *State) ExecuteCommand(id any, command string, arguments []json.RawMessage) (lsp.ExecuteCommandResponse, *lsp.ApplyWorkspaceEditParams) {
+	response := lsp.ExecuteCommandResponse{
+		Response: lsp.NewResponse(id),
+	}
+	fail := func(code lsp.ErrorCode, err error) (lsp.ExecuteCommandResponse, *lsp.ApplyWorkspaceEditParams) {
+		response.Error = &lsp.ResponseError{
+			Code:    code,
+			Message: fmt.Sprintf("%s: %v", command, err),
+		}
+		return response, nil
+	}
+
+	switch command {
+	case lsp.TrackFileCommand:
+		var uri lsp.DocumentURI
+		if err := commandArguments(arguments, &uri); err != nil {
+			return fail(lsp.InvalidParams, err)
+		}
+		// Imports are resolved on demand, so tracking the file is enough for its
+		// importers to be found.
+		s.UpdateFile(uri.Filename(), lsp.Created)
+	case lsp.MoveObjectCommand:
+		var uri lsp.DocumentURI
+		var key, newKey string
+		if err := commandArguments(arguments, &uri, &key, &newKey); err != nil {
+			return fail(lsp.InvalidParams, err)
+		}
+		edit, err := s.moveObject(uri, key, newKey)
+		if err != nil {
+			return fail(lsp.RequestFailed, err)
+		}
+		return response, &lsp.ApplyWorkspaceEditParams{
+			Label: fmt.Sprintf("Move %s to %s", key, newKey),
+			Edit:  *edit,
+		}
+	case lsp.DeleteObjectCommand:
+		var uri lsp.DocumentURI
+		var key string
+		if err := commandArguments(arguments, &uri, &key); err != nil {
+			return fail(lsp.InvalidParams, err)
+		}
+		edit, err := s.deleteObject(uri, key)
+		if err != nil {
+			return fail(lsp.RequestFailed, err)
+		}
+		return response, &lsp.ApplyWorkspaceEditParams{
+			Label: fmt.Sprintf("Delete %s", key),
+			Edit:  *edit,
+		}
+	default:
+		return fail(lsp.InvalidParams, fmt.Errorf("unknown command"))
+	}
+
+	return response, nil
+}
@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2ir"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// documentFS serves open documents from memory so that imports see unsaved
+// changes, falling back to the filesystem for everything else.
+type documentFS map[string]string
+
+func (fsys documentFS) Open(name string) (fs.File, error) {
+	text, ok := fsys[name]
+	if !ok {
+		return os.Open(name)
+	}
+
+	return &documentFile{
+		Reader: bytes.NewReader([]byte(text)),
+		name:   name,
+		size:   int64(len(text)),
+	}, nil
+}
+
+type documentFile struct {
+	*bytes.Reader
+	name string
+	size int64
+}
+
+func (f *documentFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *documentFile) Close() error               { return nil }
+func (f *documentFile) Name() string               { return f.name }
+func (f *documentFile) Size() int64                { return f.size }
+func (f *documentFile) Mode() fs.FileMode          { return 0o444 }
+func (f *documentFile) ModTime() time.Time         { return time.Time{} }
+func (f *documentFile) IsDir() bool                { return false }
+func (f *documentFile) Sys() any                   { return nil }
+
+func (s *State) fileSystem() documentFS {
+	fsys := documentFS{}
+	for uri, document := range s.Documents {
+		fsys[uri.Filename()] = document.Text
+	}
+	return fsys
+}
+
+// compileFile parses the file at path and compiles it into D2's IR.
+// The returned AST is the one referenced by the IR, so AST nodes can be matched
+// against IR references by identity.
+func (s *State) compileFile(path string) (*d2ast.Map, *d2ir.Map, error) {
+	text, err := s.fileText(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ast, err := d2parser.Parse(path, strings.NewReader(text), &d2parser.ParseOptions{
+		UTF16Pos: true,
+	})
+	if err != nil {
+		return ast, nil, err
+	}
+
+	ir, _, err := d2ir.Compile(ast, &d2ir.CompileOptions{
+		UTF16Pos: true,
+		FS:       s.fileSystem(),
+	})
+	return ast, ir, err
+}
+
+func (s *State) compileDocument(uri lsp.DocumentURI) (*d2ast.Map, *d2ir.Map, error) {
+	return s.compileFile(uri.Filename())
+}
@@ -0,0 +1,187 @@
+package analysis
+
+import (
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2ir"
+)
+
+// declarationAtPosition returns the locations where the shape or connection under
+// the cursor is first declared.
+func declarationAtPosition(ast *d2ast.Map, ir *d2ir.Map, position lsp.Position) []lsp.Location {
+	chain := nodesAtPosition(ast, position)
+	for i := len(chain) - 1; i >= 0; i-- {
+		switch node := chain[i].(type) {
+		case d2ast.String:
+			if field := fieldAtString(ir, node); field != nil {
+				if isReservedField(field) {
+					return nil
+				}
+				return []lsp.Location{fieldDeclaration(field)}
+			}
+		case *d2ast.Edge:
+			if edge := edgeAtAST(ir, node); edge != nil {
+				return []lsp.Location{toLspLocation(edge.References[0].Context_.Edge.Range)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldDeclaration returns the location of the first key path segment naming field.
+// Fields inherited by scenarios and steps resolve to the base board, imported ones
+// to the imported file. Glob patterns matching field are passed over, unless field
+// is only mentioned through them.
+func fieldDeclaration(field *d2ir.Field) lsp.Location {
+	ref := field.References[0]
+	for _, candidate := range field.References {
+		if !candidate.DueToGlob() && candidate.String != nil {
+			ref = candidate
+			break
+		}
+	}
+	return toLspLocation(ref.String.GetRange())
+}
+
+// importDefinition returns the start of the file imp imports, or the declaration of
+// the imported key for partial imports.
+func (s *State) importDefinition(importer string, imp *d2ast.Import) []lsp.Location {
+	path := importPath(importer, imp)
+	if len(imp.IDA()) == 0 {
+		if _, err := s.fileText(path); err != nil {
+			s.logger.Printf("could not resolve import %s: %v", path, err)
+			return nil
+		}
+		return []lsp.Location{{URI: lsp.File(path)}}
+	}
+
+	_, ir, err := s.compileFile(path)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", path, err)
+		return nil
+	}
+	field := ir.GetField(imp.IDA()...)
+	if field == nil {
+		return nil
+	}
+	return []lsp.Location{fieldDeclaration(field)}
+}
+
+// classAtPosition returns the class field named by the value of a class attribute
+// under the cursor.
+func classAtPosition(ast *d2ast.Map, ir *d2ir.Map, position lsp.Position) (*d2ir.Field, bool) {
+	chain := nodesAtPosition(ast, position)
+	for i := len(chain) - 1; i > 0; i-- {
+		str, ok := chain[i].(d2ast.String)
+		if !ok {
+			continue
+		}
+		key, ok := chain[i-1].(*d2ast.Key)
+		if !ok {
+			// Class lists, the array sits between the key and the name.
+			if _, isArray := chain[i-1].(*d2ast.Array); isArray && i > 1 {
+				key, ok = chain[i-2].(*d2ast.Key)
+			}
+		}
+		if !ok || key.Key == nil || key.Key.Last().Unbox().ScalarString() != "class" {
+			continue
+		}
+		attribute, ok := nodesByKey(ir)[key]
+		if !ok {
+			return nil, true
+		}
+		// Layers get a copy of the classes of their parent board.
+		return boardRoot(attribute).GetField(
+			d2ast.FlatUnquotedString("classes"),
+			d2ast.FlatUnquotedString(str.ScalarString()),
+		), true
+	}
+
+	return nil, false
+}
+
+// definitions returns every key path segment that declares field, deduplicated.
+// Boards overriding a class or variable have more than one.
+func definitions(field *d2ir.Field) []lsp.Location {
+	locations := []lsp.Location{}
+	seen := map[d2ast.Range]struct{}{}
+	for _, ref := range field.References {
+		if ref.String == nil || ref.DueToGlob() {
+			continue
+		}
+		r := ref.String.GetRange()
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		seen[r] = struct{}{}
+		locations = append(locations, toLspLocation(r))
+	}
+	return locations
+}
+
+// edgeAtAST returns the edge that the connection in the compiled AST refers to.
+func edgeAtAST(ir *d2ir.Map, node *d2ast.Edge) *d2ir.Edge {
+	var match *d2ir.Edge
+	walkIR(ir, func(n d2ir.Node) {
+		edge, ok := n.(*d2ir.Edge)
+		if !ok || match != nil {
+			return
+		}
+		for _, ref := range edge.References {
+			if ref.Context_.Edge == node && isHomeRef(edge, ref.Context_) {
+				match = edge
+				return
+			}
+		}
+	})
+
+	return match
+}
+
+func (s *State) Definition(id any, uri lsp.DocumentURI, position lsp.Position) lsp.DefinitionResponse {
+	response := lsp.DefinitionResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.Location{},
+	}
+	document, ok := s.Documents[uri]
+	if !ok {
+		return response
+	}
+
+	// Imports are resolved without compiling the document, so they can be followed
+	// even while another import is broken.
+	for _, node := range nodesAtPosition(document.AST, position) {
+		if imp, ok := node.(*d2ast.Import); ok {
+			if locations := s.importDefinition(uri.Filename(), imp); locations != nil {
+				response.Result = locations
+			}
+			return response
+		}
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+		return response
+	}
+
+	substitution, variable := variableAtPosition(document, ast, ir, nodesByKey(ir), position)
+	if substitution != nil {
+		if variable != nil {
+			response.Result = definitions(variable)
+		}
+		return response
+	}
+	if class, ok := classAtPosition(ast, ir, position); ok {
+		if class != nil {
+			response.Result = definitions(class)
+		}
+		return response
+	}
+	if locations := declarationAtPosition(ast, ir, position); locations != nil {
+		response.Result = locations
+	}
+
+	return response
+}
@@ -0,0 +1,208 @@
+package analysis_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestDefinition(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"shared.d2": "db: {\n  table\n}\n",
+	})
+	text := "...@shared\nx: {\n  y\n}\nx.y -> db.table\nscenarios: {\n  s: {\n    x.y.style.fill: red\n  }\n}\n(x.y -> db.table)[0]: again\n"
+	uri := lsp.File(filepath.Join(root, "test.d2"))
+	shared := lsp.File(filepath.Join(root, "shared.d2"))
+
+	tests := []struct {
+		name     string
+		position lsp.Position
+		want     []lsp.Location
+	}{
+		{
+			name:     "nested container",
+			position: lsp.Position{Line: 4, Character: 2},
+			want: []lsp.Location{{
+				URI:   uri,
+				Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 2}, End: lsp.Position{Line: 2, Character: 3}},
+			}},
+		},
+		{
+			name:     "imported",
+			position: lsp.Position{Line: 4, Character: 11},
+			want: []lsp.Location{{
+				URI:   shared,
+				Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 2}, End: lsp.Position{Line: 1, Character: 7}},
+			}},
+		},
+		{
+			name:     "scenario",
+			position: lsp.Position{Line: 7, Character: 6},
+			want: []lsp.Location{{
+				URI:   uri,
+				Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 2}, End: lsp.Position{Line: 2, Character: 3}},
+			}},
+		},
+		{
+			name:     "connection",
+			position: lsp.Position{Line: 10, Character: 6},
+			want: []lsp.Location{{
+				URI:   uri,
+				Range: lsp.Range{Start: lsp.Position{Line: 4, Character: 0}, End: lsp.Position{Line: 4, Character: 15}},
+			}},
+		},
+		{
+			name:     "keyword",
+			position: lsp.Position{Line: 5, Character: 3},
+			want:     []lsp.Location{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			state.OpenDocument(uri, 1, text)
+
+			response := state.Definition(1, uri, test.position)
+			if diff := cmp.Diff(test.want, response.Result); diff != "" {
+				t.Errorf("Definition(%v) mismatch (-want +got):\n%s", test.position, diff)
+			}
+		})
+	}
+}
+
+func TestGlobDefinition(t *testing.T) {
+	state := newTestState(t)
+	// The glob declares a.b before it is written out.
+	uri := openTestDocument(t, state, "test.d2", "a\n*.b: 1\na.b -> c\n")
+
+	response := state.Definition(1, uri, lsp.Position{Line: 2, Character: 2})
+	want := []lsp.Location{{
+		URI:   uri,
+		Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 2}, End: lsp.Position{Line: 2, Character: 3}},
+	}}
+	if diff := cmp.Diff(want, response.Result); diff != "" {
+		t.Errorf("Definition() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefinitionClosedDocument(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{"test.d2": "a -> b\n"})
+	state := newTestState(t)
+
+	response := state.Definition(1, lsp.File(filepath.Join(root, "test.d2")), lsp.Position{})
+	if diff := cmp.Diff([]lsp.Location{}, response.Result); diff != "" {
+		t.Errorf("Definition() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestImportDefinition(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"models.d2":        "vars: {x: 1}\nuser: {\n  name\n}\n",
+		"shared/styles.d2": "classes: {k: {style.fill: red}}\n",
+	})
+	text := "...@shared/styles\nu: @models.user\nv: @missing\n"
+	uri := lsp.File(filepath.Join(root, "test.d2"))
+
+	tests := []struct {
+		name     string
+		position lsp.Position
+		want     []lsp.Location
+	}{
+		{
+			name:     "file",
+			position: lsp.Position{Line: 0, Character: 10},
+			want:     []lsp.Location{{URI: lsp.File(filepath.Join(root, "shared", "styles.d2"))}},
+		},
+		{
+			name:     "key",
+			position: lsp.Position{Line: 1, Character: 12},
+			want: []lsp.Location{{
+				URI:   lsp.File(filepath.Join(root, "models.d2")),
+				Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 0}, End: lsp.Position{Line: 1, Character: 4}},
+			}},
+		},
+		{
+			name:     "missing",
+			position: lsp.Position{Line: 2, Character: 6},
+			want:     []lsp.Location{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			state.OpenDocument(uri, 1, text)
+
+			response := state.Definition(1, uri, test.position)
+			if diff := cmp.Diff(test.want, response.Result); diff != "" {
+				t.Errorf("Definition(%v) mismatch (-want +got):\n%s", test.position, diff)
+			}
+		})
+	}
+}
+
+func TestClassAndVariableDefinition(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"theme.d2": "classes: {primary: {style.fill: blue}}\n",
+		"vars.d2":  "brand: red\n",
+	})
+	text := "...@theme\nvars: {\n  ...@vars\n}\nclasses: {faded: {style.opacity: 0.4}}\na.class: [primary; faded]\na.style.stroke: ${brand}\nlayers: {\n  l: {\n    b.class: faded\n    b.label: ${brand}\n  }\n}\n"
+	uri := lsp.File(filepath.Join(root, "test.d2"))
+
+	tests := []struct {
+		name     string
+		position lsp.Position
+		want     []string
+	}{
+		{
+			name:     "imported class",
+			position: lsp.Position{Line: 5, Character: 12},
+			want:     []string{"theme.d2:1:11"},
+		},
+		{
+			name:     "class in list",
+			position: lsp.Position{Line: 5, Character: 21},
+			want:     []string{"test.d2:5:11"},
+		},
+		{
+			name:     "imported variable",
+			position: lsp.Position{Line: 6, Character: 19},
+			want:     []string{"vars.d2:1:1"},
+		},
+		{
+			name:     "class in layer",
+			position: lsp.Position{Line: 9, Character: 14},
+			want:     []string{"test.d2:5:11"},
+		},
+		{
+			name:     "variable in layer",
+			position: lsp.Position{Line: 10, Character: 16},
+			want:     []string{"vars.d2:1:1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			state.OpenDocument(uri, 1, text)
+
+			response := state.Definition(1, uri, test.position)
+			got := []string{}
+			for _, location := range response.Result {
+				got = append(got, fmt.Sprintf(
+					"%s:%d:%d",
+					filepath.Base(location.URI.Filename()),
+					location.Range.Start.Line+1,
+					location.Range.Start.Character+1,
+				))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Definition(%v) mismatch (-want +got):\n%s", test.position, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2oracle"
+)
+
+// deleteAction offers to delete the object under the cursor. The edit is only
+// worked out when the command runs, since the oracle recompiles the document.
+func (s *State) deleteAction(uri lsp.DocumentURI, position lsp.Position) *lsp.CodeAction {
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		return nil
+	}
+	target := referenceTarget(ast, ir, position)
+	if target == nil || isReservedField(target) || boardPath(target) != "" || !declaredIn(target, uri.Filename()) {
+		return nil
+	}
+
+	title := "Delete shape and its connections"
+	return &lsp.CodeAction{
+		Title: title,
+		Kind:  lsp.Refactor,
+		Command: &lsp.Command{
+			Title:     title,
+			Command:   lsp.DeleteObjectCommand,
+			Arguments: []any{uri, objectID(target)},
+		},
+	}
+}
+
+// deleteObject removes the object with key with D2's oracle, along with every
+// attribute set on it and every connection to it. Edges left behind would declare
+// the object again. Children of a container are kept in its parent.
+func (s *State) deleteObject(uri lsp.DocumentURI, key string) (*lsp.WorkspaceEdit, error) {
+	path := uri.Filename()
+	text, err := s.fileText(path)
+	if err != nil {
+		return nil, err
+	}
+	_, ir, err := s.compileFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if objectByID(ir, key) == nil {
+		return nil, fmt.Errorf("%s is not declared in %s", key, path)
+	}
+	g, err := s.compileGraph(path, text)
+	if err != nil {
+		return nil, err
+	}
+	formatted := d2format.Format(g.AST)
+	g, err = d2oracle.Delete(g, nil, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lsp.WorkspaceEdit{
+		Changes: map[lsp.DocumentURI][]lsp.TextEdit{
+			uri: MergeEdits(text, formatted, d2format.Format(g.AST)),
+		},
+	}, nil
+}
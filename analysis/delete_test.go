@@ -0,0 +1,91 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestDeleteObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		position lsp.Position
+		title    string
+		want     string
+	}{
+		{
+			name:     "shape",
+			text:     "a\nb\na -> b: hi\nc -> a\na.style.fill: red\nx: {\n  a.shape: circle\n}\nx.y -> a\n",
+			position: lsp.Position{Line: 0, Character: 0},
+			title:    "Delete shape and its connections",
+			want:     "b\nc\n\nx: {\n  a.shape: circle\n}\nx.y\n",
+		},
+		{
+			name:     "container",
+			text:     "x: {\n  a -> b\n  c\n}\nx.style.fill: red\nd -> x.a\n",
+			position: lsp.Position{Line: 4, Character: 0},
+			title:    "Delete shape and its connections",
+			want:     "a -> b\nc\n\nd -> a\n",
+		},
+		{
+			name:     "layout kept",
+			text:     "a\nc:   {x;   y}\n\n\nb\na -> b\n",
+			position: lsp.Position{Line: 0, Character: 0},
+			title:    "Delete shape and its connections",
+			want:     "c:   {x;   y}\n\n\nb\n",
+		},
+		{
+			name:     "attribute",
+			text:     "a.style.fill: red\n",
+			position: lsp.Position{Line: 0, Character: 9},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			uri := openTestDocument(t, state, "test.d2", test.text)
+
+			context := lsp.CodeActionContext{Only: []lsp.CodeActionKind{lsp.Refactor}}
+			response := state.CodeActions(1, uri, lsp.Range{Start: test.position, End: test.position}, context)
+			var action *lsp.CodeAction
+			for i := range response.Result {
+				if response.Result[i].Kind == lsp.Refactor {
+					action = &response.Result[i]
+				}
+			}
+			if test.title == "" {
+				if action != nil {
+					t.Fatalf("CodeActions() = %+v, want no delete action", *action)
+				}
+				return
+			}
+			if action == nil || action.Title != test.title {
+				t.Fatalf("CodeActions() = %+v, want %q", response.Result, test.title)
+			}
+			if action.Edit != nil || action.Command == nil {
+				t.Fatalf("CodeActions() = %+v, want a command computing the edit", *action)
+			}
+
+			arguments := []json.RawMessage{}
+			for _, argument := range action.Command.Arguments {
+				raw, err := json.Marshal(argument)
+				if err != nil {
+					t.Fatal(err)
+				}
+				arguments = append(arguments, raw)
+			}
+			result, apply := state.ExecuteCommand(2, action.Command.Command, arguments)
+			if result.Error != nil {
+				t.Fatalf("ExecuteCommand() error = %+v", result.Error)
+			}
+			got := applyEdits(test.text, apply.Edit.Changes[uri])
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
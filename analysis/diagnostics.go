@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2compiler"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// Diagnostic codes, code actions look their quick fixes up by these.
+const (
+	codeUnclosedMap              = "unclosed-map"
+	codeUnclosedArray            = "unclosed-array"
+	codeUnclosedDoubleQuote      = "unclosed-double-quote"
+	codeUnclosedSingleQuote      = "unclosed-single-quote"
+	codeUnclosedSubstitution     = "unclosed-substitution"
+	codeUnexpectedText           = "unexpected-text"
+	codeUnexpectedMapTermination = "unexpected-map-termination"
+	codeInvalidStyleKeyword      = "invalid-style-keyword"
+	codeUnknownShape             = "unknown-shape"
+	codeInvalidColor             = "invalid-color"
+	codeInvalidDirection         = "invalid-direction"
+	codeInvalidBoolean           = "invalid-boolean"
+	codeOutOfRange               = "out-of-range"
+)
+
+// D2 doesn't give its errors a code, so they are recognised by their message.
+var diagnosticCodes = []struct {
+	pattern *regexp.Regexp
+	code    string
+}{
+	{regexp.MustCompile(`maps must be terminated with }`), codeUnclosedMap},
+	{regexp.MustCompile(`arrays must be terminated with ]`), codeUnclosedArray},
+	{regexp.MustCompile(`double quoted strings must be terminated with "`), codeUnclosedDoubleQuote},
+	{regexp.MustCompile(`single quoted strings must be terminated with '`), codeUnclosedSingleQuote},
+	{regexp.MustCompile(`substitutions must be terminated by }`), codeUnclosedSubstitution},
+	{regexp.MustCompile(`unexpected text after`), codeUnexpectedText},
+	{regexp.MustCompile(`unexpected map termination character }`), codeUnexpectedMapTermination},
+	{regexp.MustCompile(`invalid style keyword: `), codeInvalidStyleKeyword},
+	{regexp.MustCompile(`unknown shape `), codeUnknownShape},
+	{regexp.MustCompile(`to be a valid named color`), codeInvalidColor},
+	{regexp.MustCompile(`direction must be one of`), codeInvalidDirection},
+	{regexp.MustCompile(`to be true or false`), codeInvalidBoolean},
+	{regexp.MustCompile(`to be a number between`), codeOutOfRange},
+}
+
+func diagnosticCode(message string) string {
+	for _, diagnostic := range diagnosticCodes {
+		if diagnostic.pattern.MatchString(message) {
+			return diagnostic.code
+		}
+	}
+	return ""
+}
+
+// compileErrors returns the errors found while compiling a document that parses.
+// Errors in imported files are left to those files.
+func (s *State) compileErrors(uri lsp.DocumentURI) []d2ast.Error {
+	path := uri.Filename()
+	_, _, err := d2compiler.Compile(path, strings.NewReader(s.Documents[uri].Text), &d2compiler.CompileOptions{
+		UTF16Pos: true,
+		FS:       s.fileSystem(),
+	})
+	if err == nil {
+		return nil
+	}
+
+	parseErr, ok := err.(*d2parser.ParseError)
+	if !ok {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+		return nil
+	}
+	errors := []d2ast.Error{}
+	for _, e := range parseErr.Errors {
+		if e.Range.Path == path {
+			errors = append(errors, e)
+		}
+	}
+	return errors
+}
+
+// documentDiagnostics reports parse errors, and compile errors once the document
+// parses.
+func (s *State) documentDiagnostics(uri lsp.DocumentURI) []lsp.Diagnostic {
+	errors := s.Documents[uri].Errors
+	if len(errors) == 0 {
+		errors = s.compileErrors(uri)
+	}
+	return getDiagnosticsFromAST(errors)
+}
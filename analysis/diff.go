@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/akedrou/textdiff"
+	"github.com/akedrou/textdiff/lcs"
 	"github.com/ram02z/d2-language-server/lsp"
 )
 
@@ -51,3 +52,90 @@ func ComputeTextEdits(before, after string) []lsp.TextEdit {
 
 	return result
 }
+
+// textLines splits text into lines, each keeping its line break.
+func textLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineDiff returns the hunks turning the lines a into the lines b.
+func lineDiff(a, b []string) []lcs.Diff {
+	ids := map[string]rune{}
+	encode := func(lines []string) []rune {
+		runes := make([]rune, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = rune(len(ids))
+				ids[line] = id
+			}
+			runes[i] = id
+		}
+		return runes
+	}
+	return lcs.DiffRunes(encode(a), encode(b))
+}
+
+// shift returns how many lines diffs add before line. Insertions at line count
+// when before is set.
+func shift(diffs []lcs.Diff, line int, before bool) int {
+	n := 0
+	for _, d := range diffs {
+		if d.End < line || d.End == line && (d.Start < d.End || before) {
+			n += (d.ReplEnd - d.ReplStart) - (d.End - d.Start)
+		}
+	}
+	return n
+}
+
+// MergeEdits returns the edits to text making the changes that turned formatted,
+// text as D2 formats it, into changed. Only the lines those changes touch are
+// formatted, the rest of text keeps its own layout.
+func MergeEdits(text, formatted, changed string) []lsp.TextEdit {
+	lines, base, after := textLines(text), textLines(formatted), textLines(changed)
+	layout, changes := lineDiff(base, lines), lineDiff(base, after)
+	overlaps := func(a, b lcs.Diff) bool {
+		return a.Start < b.End && b.Start < a.End
+	}
+
+	var result []lsp.TextEdit
+	for i := 0; i < len(changes); {
+		// Grow the hunk over the changes and the differences in layout it overlaps,
+		// the lines of text they cover are formatted along with it.
+		hunk := lcs.Diff{Start: changes[i].Start, End: changes[i].End}
+		for grown := true; grown; {
+			grown = false
+			for _, diffs := range [][]lcs.Diff{layout, changes[i:]} {
+				for _, d := range diffs {
+					if overlaps(hunk, d) && (d.Start < hunk.Start || d.End > hunk.End) {
+						hunk.Start, hunk.End = min(hunk.Start, d.Start), max(hunk.End, d.End)
+						grown = true
+					}
+				}
+			}
+		}
+		first := i
+		for i < len(changes) && (changes[i].Start < hunk.End || changes[i].Start == hunk.Start) {
+			i++
+		}
+
+		empty := hunk.Start == hunk.End
+		start := hunk.Start + shift(layout, hunk.Start, true)
+		end := hunk.End + shift(layout, hunk.End, empty)
+		newText := strings.Join(after[hunk.Start+shift(changes[:first], hunk.Start, true):hunk.End+shift(changes[:i], hunk.End, true)], "")
+
+		endPosition := lsp.Position{Line: end}
+		if end == len(lines) && end > 0 && !strings.HasSuffix(text, "\n") {
+			endPosition = lsp.Position{Line: end - 1, Character: utf16Len(lines[end-1])}
+		}
+		result = append(result, lsp.TextEdit{
+			Range:   lsp.Range{Start: lsp.Position{Line: start}, End: endPosition},
+			NewText: newText,
+		})
+	}
+	return result
+}
@@ -102,3 +102,57 @@ func TestComputeTextEdits(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeEdits(t *testing.T) {
+	lines := func(startLine, startChar, endLine, endChar int) lsp.Range {
+		return lsp.Range{
+			Start: lsp.Position{Line: startLine, Character: startChar},
+			End:   lsp.Position{Line: endLine, Character: endChar},
+		}
+	}
+	tests := []struct {
+		name      string
+		text      string
+		formatted string
+		changed   string
+		expected  []lsp.TextEdit
+	}{
+		{
+			name:      "layout kept",
+			text:      "a\nb:   c\n",
+			formatted: "a\nb: c\n",
+			changed:   "b: c\n",
+			expected:  []lsp.TextEdit{{Range: lines(0, 0, 1, 0), NewText: ""}},
+		},
+		{
+			name:      "changed line formatted",
+			text:      "a:   1\nb\n",
+			formatted: "a: 1\nb\n",
+			changed:   "a: 2\nb\n",
+			expected:  []lsp.TextEdit{{Range: lines(0, 0, 1, 0), NewText: "a: 2\n"}},
+		},
+		{
+			name:      "insertion after extra lines",
+			text:      "a\n\n\nb\n",
+			formatted: "a\n\nb\n",
+			changed:   "a\n\nb\nc\n",
+			expected:  []lsp.TextEdit{{Range: lines(4, 0, 4, 0), NewText: "c\n"}},
+		},
+		{
+			name:      "no trailing newline",
+			text:      "a\nb",
+			formatted: "a\nb\n",
+			changed:   "a\n",
+			expected:  []lsp.TextEdit{{Range: lines(1, 0, 1, 1), NewText: ""}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := analysis.MergeEdits(test.text, test.formatted, test.changed)
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Errorf("MergeEdits() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
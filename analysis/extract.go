@@ -0,0 +1,160 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+)
+
+// extractableContainer returns the innermost container declared with a map under
+// the cursor. Edges, globs and reserved fields like style are skipped, as are maps
+// that only set attributes.
+func extractableContainer(ast *d2ast.Map, position lsp.Position) *d2ast.Key {
+	chain := nodesAtPosition(ast, position)
+	for i := len(chain) - 1; i >= 0; i-- {
+		key, ok := chain[i].(*d2ast.Key)
+		if !ok || key.Key == nil || len(key.Edges) > 0 || key.Value.Map == nil {
+			continue
+		}
+		if key.Ampersand || key.NotAmpersand || key.Key.HasGlob() {
+			continue
+		}
+		if _, reserved := d2ast.ReservedKeywords[key.Key.Last().Unbox().ScalarString()]; reserved {
+			continue
+		}
+		if hasChildren(key.Value.Map) {
+			return key
+		}
+	}
+	return nil
+}
+
+// hasChildren reports whether m declares shapes or edges, like isContainer does for
+// compiled fields.
+func hasChildren(m *d2ast.Map) bool {
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == nil {
+			continue
+		}
+		if len(key.Edges) > 0 {
+			return true
+		}
+		if key.Key != nil && len(key.Key.Path) > 0 {
+			if _, reserved := d2ast.ReservedKeywords[key.Key.Path[0].Unbox().ScalarString()]; !reserved {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var fileNameSeparators = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// extractedPath returns a path for a new file next to dir named after the container,
+// adding a number when the name is taken.
+func (s *State) extractedPath(dir, container string) string {
+	name := strings.Trim(fileNameSeparators.ReplaceAllString(strings.ToLower(container), "-"), "-")
+	if name == "" {
+		name = "container"
+	}
+
+	path := filepath.Join(dir, name+".d2")
+	for i := 2; ; i++ {
+		if _, err := s.fileText(path); err != nil {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.d2", name, i))
+	}
+}
+
+// containerBody returns the text between the braces of m, dedented to the start of
+// the line.
+func containerBody(text string, m *d2ast.Map) string {
+	start := textOffset(text, toLspPosition(m.Range.Start)) + len("{")
+	end := textOffset(text, toLspPosition(m.Range.End)) - len("}")
+	lines := strings.Split(text[start:end], "\n")
+	if strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " \t")); indent == -1 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+		lines[i] = strings.TrimRight(lines[i], " \t\r")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// extractContainer moves the body of the container under the cursor into a new file
+// next to the document and imports it in its place. The new file is tracked once the
+// client has applied the edit.
+func (s *State) extractContainer(uri lsp.DocumentURI, position lsp.Position) *lsp.CodeAction {
+	document := s.Documents[uri]
+	key := extractableContainer(document.AST, position)
+	if key == nil {
+		return nil
+	}
+
+	name := key.Key.Last().Unbox().ScalarString()
+	path := s.extractedPath(filepath.Dir(uri.Filename()), name)
+	body := containerBody(document.Text, key.Value.Map)
+
+	// The label can't stay next to an import, so it moves into the new file.
+	replaced := toLspRange(key.Value.Map.Range)
+	if primary := key.Primary.Unbox(); primary != nil {
+		body = "label: " + d2format.Format(primary) + "\n" + body
+		replaced.Start = toLspPosition(primary.GetRange().Start)
+	}
+
+	newFile := lsp.File(path)
+	relPath := strings.TrimSuffix(filepath.Base(path), ".d2")
+	version := document.Version
+	return &lsp.CodeAction{
+		Title: fmt.Sprintf("Extract %s into %s", d2format.Format(key.Key), filepath.Base(path)),
+		Kind:  lsp.RefactorExtract,
+		Edit: &lsp.WorkspaceEdit{
+			DocumentChanges: []any{
+				lsp.NewCreateFile(newFile),
+				lsp.TextDocumentEdit{
+					TextDocument: lsp.OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: newFile},
+					},
+					Edits: []lsp.TextEdit{{NewText: body}},
+				},
+				lsp.TextDocumentEdit{
+					TextDocument: lsp.OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: uri},
+						Version:                &version,
+					},
+					Edits: []lsp.TextEdit{{Range: replaced, NewText: "@" + relPath}},
+				},
+			},
+		},
+		Command: &lsp.Command{
+			Title:     "Track extracted file",
+			Command:   lsp.TrackFileCommand,
+			Arguments: []any{newFile},
+		},
+	}
+}
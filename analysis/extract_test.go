@@ -0,0 +1,144 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestExtractContainer(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		position lsp.Position
+		title    string
+		file     string
+		body     string
+		want     string
+	}{
+		{
+			name:     "nested container",
+			text:     "backend: {\n  api: {\n    handler -> db\n\n    db.shape: cylinder\n  }\n}\n",
+			position: lsp.Position{Line: 2, Character: 5},
+			title:    "Extract api into api-2.d2",
+			file:     "api-2.d2",
+			body:     "handler -> db\n\ndb.shape: cylinder\n",
+			want:     "backend: {\n  api: @api-2\n}\n",
+		},
+		{
+			name:     "label",
+			text:     "Web Tier: Frontend {a; b}\n",
+			position: lsp.Position{Line: 0, Character: 2},
+			title:    "Extract Web Tier into web-tier.d2",
+			file:     "web-tier.d2",
+			body:     "label: Frontend\na; b\n",
+			want:     "Web Tier: @web-tier\n",
+		},
+		{
+			name:     "style",
+			text:     "a.style: {\n  fill: red\n}\n",
+			position: lsp.Position{Line: 1, Character: 3},
+		},
+		{
+			name:     "attributes only",
+			text:     "a: {style: {fill: red}}\n",
+			position: lsp.Position{Line: 0, Character: 0},
+		},
+		{
+			name:     "edge",
+			text:     "a -> b: {\n  style.stroke: red\n}\n",
+			position: lsp.Position{Line: 0, Character: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := writeTestFiles(t, map[string]string{"api.d2": "x\n"})
+			uri := lsp.File(filepath.Join(root, "test.d2"))
+			state := newTestState(t)
+			state.OpenDocument(uri, 1, test.text)
+
+			context := lsp.CodeActionContext{Only: []lsp.CodeActionKind{lsp.RefactorExtract}}
+			response := state.CodeActions(1, uri, lsp.Range{Start: test.position, End: test.position}, context)
+			if test.title == "" {
+				if len(response.Result) != 0 {
+					t.Fatalf("CodeActions() = %+v, want none", response.Result)
+				}
+				return
+			}
+			if len(response.Result) != 1 {
+				t.Fatalf("CodeActions() returned %d actions, want 1", len(response.Result))
+			}
+
+			action := response.Result[0]
+			if action.Title != test.title {
+				t.Errorf("title = %q, want %q", action.Title, test.title)
+			}
+			file := lsp.File(filepath.Join(root, test.file))
+			changes := action.Edit.DocumentChanges
+			if diff := cmp.Diff([]any{
+				lsp.NewCreateFile(file),
+				lsp.TextDocumentEdit{
+					TextDocument: lsp.OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: file},
+					},
+					Edits: []lsp.TextEdit{{NewText: test.body}},
+				},
+			}, changes[:2]); diff != "" {
+				t.Errorf("new file mismatch (-want +got):\n%s", diff)
+			}
+			edit := changes[2].(lsp.TextDocumentEdit)
+			if got := applyEdits(test.text, edit.Edits); got != test.want {
+				t.Errorf("document = %q, want %q", got, test.want)
+			}
+			if action.Command == nil || action.Command.Command != lsp.TrackFileCommand {
+				t.Errorf("command = %+v, want %s", action.Command, lsp.TrackFileCommand)
+			}
+		})
+	}
+}
+
+func TestTrackExtractedFile(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{})
+	uri := lsp.File(filepath.Join(root, "test.d2"))
+	state := newTestState(t)
+	state.AddWorkspaceFolders([]lsp.WorkspaceFolder{{URI: lsp.File(root), Name: "test"}})
+	state.OpenDocument(uri, 1, "backend: {\n  api -> db\n}\n")
+
+	context := lsp.CodeActionContext{Only: []lsp.CodeActionKind{lsp.RefactorExtract}}
+	action := state.CodeActions(1, uri, lsp.Range{Start: lsp.Position{Line: 1, Character: 3}}, context).Result[0]
+
+	// Apply the edit the way a client would.
+	writeTestFilesIn(t, root, map[string]string{"backend.d2": "api -> db\n"})
+	if diagnostics := state.UpdateDocument(uri, 2, "backend: @backend\nbackend.db.shape: cylinder\n"); len(diagnostics) != 0 {
+		t.Fatalf("UpdateDocument() = %+v, want no diagnostics", diagnostics)
+	}
+	arguments := []json.RawMessage{}
+	for _, argument := range action.Command.Arguments {
+		raw, err := json.Marshal(argument)
+		if err != nil {
+			t.Fatal(err)
+		}
+		arguments = append(arguments, raw)
+	}
+	if response, _ := state.ExecuteCommand(2, action.Command.Command, arguments); response.Error != nil {
+		t.Fatalf("ExecuteCommand() error = %+v", response.Error)
+	}
+
+	path := filepath.Join(root, "backend.d2")
+	if files := state.WorkspaceFolders[lsp.File(root)].Files; !slices.Contains(files, path) {
+		t.Errorf("workspace files = %v, want %s", files, path)
+	}
+	references := state.References(3, lsp.File(path), lsp.Position{Line: 0, Character: 8}, true)
+	got := []string{}
+	for _, location := range references.Result {
+		got = append(got, filepath.Base(location.URI.Filename()))
+	}
+	if diff := cmp.Diff([]string{"backend.d2", "test.d2"}, got); diff != "" {
+		t.Errorf("References() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,216 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+)
+
+// styleBlock is a style map set on a shape.
+type styleBlock struct {
+	key *d2ast.Key
+	// The key declaring the shape, which is key itself for keys like a.style.
+	owner *d2ast.Key
+	// The formatted attributes, sorted so the order they are written in doesn't
+	// matter.
+	attributes string
+}
+
+// styleBlocks returns the style maps set on shapes in m that don't use a class yet.
+// Reserved fields like classes aren't shapes, so they are skipped.
+func styleBlocks(m *d2ast.Map, owner *d2ast.Key) []styleBlock {
+	blocks := []styleBlock{}
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == nil || !isPlainKey(key) || key.Key.HasGlob() {
+			continue
+		}
+		path := key.Key.Path
+		last := path[len(path)-1].Unbox()
+		if last.ScalarString() == "style" {
+			blockOwner := owner
+			if len(path) > 1 {
+				blockOwner = key
+			}
+			attributes := styleAttributes(key)
+			if attributes != "" && blockOwner != nil && !hasClass(m, path[:len(path)-1]) {
+				blocks = append(blocks, styleBlock{key: key, owner: blockOwner, attributes: attributes})
+			}
+			continue
+		}
+		if _, reserved := d2ast.ReservedKeywords[last.ScalarString()]; reserved {
+			continue
+		}
+		if key.Value.Map != nil {
+			blocks = append(blocks, styleBlocks(key.Value.Map, key)...)
+		}
+	}
+	return blocks
+}
+
+// styleAttributes returns the attributes set by the style key, or nothing when its
+// map holds anything else.
+func styleAttributes(key *d2ast.Key) string {
+	if key.Value.Map == nil || key.Primary.Unbox() != nil || len(key.Value.Map.Nodes) == 0 {
+		return ""
+	}
+	attributes := []string{}
+	for _, node := range key.Value.Map.Nodes {
+		if node.MapKey == nil || !isPlainKey(node.MapKey) || node.MapKey.Value.Map != nil {
+			return ""
+		}
+		attributes = append(attributes, d2format.Format(node.MapKey))
+	}
+	slices.Sort(attributes)
+	return strings.Join(attributes, "\n")
+}
+
+// hasClass reports whether m sets the class of the shape at prefix.
+func hasClass(m *d2ast.Map, prefix []*d2ast.StringBox) bool {
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == nil || !isPlainKey(key) || len(key.Key.Path) != len(prefix)+1 {
+			continue
+		}
+		if key.Key.Path[len(prefix)].Unbox().ScalarString() != "class" {
+			continue
+		}
+		same := true
+		for i, segment := range prefix {
+			if !sameKeySegment(segment.Unbox(), key.Key.Path[i].Unbox()) {
+				same = false
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicatedStyles returns the style blocks to extract. With a selection, it is the
+// largest set of identical blocks on shapes starting in it. Otherwise, it is every
+// block identical to the one on the shape under the cursor.
+func duplicatedStyles(blocks []styleBlock, rng lsp.Range) []styleBlock {
+	groups := map[string][]styleBlock{}
+	if rng.Start == rng.End {
+		attributes := ""
+		for _, block := range blocks {
+			if rangeContains(block.owner.Range, rng.Start) {
+				attributes = block.attributes
+			}
+		}
+		for _, block := range blocks {
+			if attributes != "" && block.attributes == attributes {
+				groups[attributes] = append(groups[attributes], block)
+			}
+		}
+	} else {
+		for _, block := range blocks {
+			start := toLspPosition(block.owner.Range.Start)
+			if comparePositions(rng.Start, start) <= 0 && comparePositions(start, rng.End) <= 0 {
+				groups[block.attributes] = append(groups[block.attributes], block)
+			}
+		}
+	}
+
+	var duplicated []styleBlock
+	for _, group := range groups {
+		// Ties go to the group written first, so the result doesn't depend on map order.
+		if len(group) > len(duplicated) || (len(group) == len(duplicated) && comparePositions(toLspPosition(group[0].key.Range.Start), toLspPosition(duplicated[0].key.Range.Start)) < 0) {
+			duplicated = group
+		}
+	}
+	if len(duplicated) < 2 {
+		return nil
+	}
+	return duplicated
+}
+
+// className returns a class name classes doesn't declare yet.
+func className(classes *d2ast.Key) string {
+	taken := func(name string) bool {
+		if classes == nil {
+			return false
+		}
+		for _, node := range classes.Value.Map.Nodes {
+			if key := node.MapKey; key != nil && isPlainKey(key) && sameKeySegment(key.Key.Path[0].Unbox(), d2ast.FlatUnquotedString(name)) {
+				return true
+			}
+		}
+		return false
+	}
+	name := "shared-style"
+	for i := 2; taken(name); i++ {
+		name = fmt.Sprintf("shared-style-%d", i)
+	}
+	return name
+}
+
+// extractClass moves a style repeated across shapes into a class, and sets that
+// class on the shapes instead.
+func (s *State) extractClass(uri lsp.DocumentURI, rng lsp.Range) *lsp.CodeAction {
+	text := s.Documents[uri].Text
+	root := freshAST(uri, text)
+	if root == nil {
+		return nil
+	}
+	blocks := duplicatedStyles(styleBlocks(root, nil), rng)
+	if blocks == nil {
+		return nil
+	}
+
+	// The class gets a copy of the first block.
+	classes := siblingMap(root, d2ast.FlatUnquotedString("classes"), nil)
+	name := className(classes)
+	style := *blocks[0].key
+	style.Key = &d2ast.KeyPath{Path: style.Key.Path[len(style.Key.Path)-1:]}
+	m := lineMap(1)
+	onLine(&style, m)
+	m.Nodes = []d2ast.MapNodeBox{d2ast.MakeMapNodeBox(&style)}
+	class := &d2ast.Key{
+		Key:   &d2ast.KeyPath{Path: []*d2ast.StringBox{d2ast.RawStringBox(name, true)}},
+		Value: d2ast.MakeValueBox(m),
+	}
+
+	edits := []lsp.TextEdit{}
+	if classes != nil {
+		mergeKey(classes.Value.Map, class)
+		edits = append(edits, lsp.TextEdit{
+			Range:   toLspRange(classes.Range),
+			NewText: formatAt(text, classes),
+		})
+	} else {
+		classes = &d2ast.Key{
+			Key:   &d2ast.KeyPath{Path: []*d2ast.StringBox{d2ast.RawStringBox("classes", true)}},
+			Value: d2ast.MakeValueBox(lineMap(1)),
+		}
+		mergeKey(classes.Value.Map, class)
+		// Classes go first, like they are usually written.
+		edits = append(edits, lsp.TextEdit{NewText: d2format.Format(classes) + "\n\n"})
+	}
+
+	for _, block := range blocks {
+		path := slices.Clone(block.key.Key.Path[:len(block.key.Key.Path)-1])
+		path = append(path, d2ast.RawStringBox("class", true))
+		edits = append(edits, lsp.TextEdit{
+			Range:   toLspRange(block.key.Range),
+			NewText: d2format.Format(&d2ast.KeyPath{Path: path}) + ": " + name,
+		})
+	}
+	slices.SortFunc(edits, func(a, b lsp.TextEdit) int {
+		return comparePositions(a.Range.Start, b.Range.Start)
+	})
+
+	return &lsp.CodeAction{
+		Title: fmt.Sprintf("Extract style of %d shapes into class %s", len(blocks), name),
+		Kind:  lsp.RefactorExtract,
+		Edit: &lsp.WorkspaceEdit{
+			Changes: map[lsp.DocumentURI][]lsp.TextEdit{uri: edits},
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestExtractClass(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		rng   lsp.Range
+		title string
+		want  string
+	}{
+		{
+			name: "selection",
+			text: "a: {\n  style: {\n    fill: red\n    stroke: blue\n  }\n}\nb.style: {stroke: blue; fill: red}\nc.style.fill: green\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 0},
+				End:   lsp.Position{Line: 7, Character: 0},
+			},
+			title: "Extract style of 2 shapes into class shared-style",
+			want:  "classes: {\n  shared-style: {\n    style: {\n      fill: red\n      stroke: blue\n    }\n  }\n}\n\na: {\n  class: shared-style\n}\nb.class: shared-style\nc.style.fill: green\n",
+		},
+		{
+			name: "cursor with existing classes",
+			text: "classes: {\n  shared-style: {shape: circle}\n}\nx: {\n  a.style: {fill: red}\n}\nb.style: {fill: red}\nc.style: {fill: blue}\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 6, Character: 0},
+				End:   lsp.Position{Line: 6, Character: 0},
+			},
+			title: "Extract style of 2 shapes into class shared-style-2",
+			want:  "classes: {\n  shared-style: {shape: circle}\n  shared-style-2: {\n    style: {fill: red}\n  }\n}\nx: {\n  a.class: shared-style-2\n}\nb.class: shared-style-2\nc.style: {fill: blue}\n",
+		},
+		{
+			name: "shapes with a class",
+			text: "a: {class: x; style: {fill: red}}\nb.style: {fill: red}\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 0},
+				End:   lsp.Position{Line: 1, Character: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			uri := openTestDocument(t, state, "test.d2", test.text)
+
+			context := lsp.CodeActionContext{Only: []lsp.CodeActionKind{lsp.RefactorExtract}}
+			response := state.CodeActions(1, uri, test.rng, context)
+			if test.title == "" {
+				if len(response.Result) != 0 {
+					t.Fatalf("CodeActions() = %+v, want none", response.Result)
+				}
+				return
+			}
+			var action *lsp.CodeAction
+			for i := range response.Result {
+				if response.Result[i].Title == test.title {
+					action = &response.Result[i]
+				}
+			}
+			if action == nil {
+				t.Fatalf("CodeActions() = %+v, want %q", response.Result, test.title)
+			}
+			got := applyEdits(test.text, action.Edit.Changes[uri])
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("extracted document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+)
+
+// literal is a string value set on a key, like a label or a colour.
+type literal struct {
+	value d2ast.Scalar
+	key   *d2ast.Key
+	// The attribute the value sets, label for values set on shapes and edges.
+	attribute string
+}
+
+// literalAttribute returns the attribute that value, set on key, sets.
+func literalAttribute(key *d2ast.Key, value d2ast.Scalar) string {
+	if key.Key == nil || value == key.Primary.Unbox() {
+		return "label"
+	}
+	last := key.Key.Last().Unbox().ScalarString()
+	if _, reserved := d2ast.ReservedKeywords[last]; reserved {
+		return last
+	}
+	return "label"
+}
+
+// literalString returns the text of value when it is a string without
+// substitutions.
+func literalString(value d2ast.Scalar) (string, bool) {
+	switch value := value.(type) {
+	case *d2ast.UnquotedString:
+		if len(value.Value) == 1 && value.Value[0].String != nil && value.Pattern == nil {
+			return *value.Value[0].String, true
+		}
+	case *d2ast.DoubleQuotedString:
+		if len(value.Value) == 1 && value.Value[0].String != nil {
+			return *value.Value[0].String, true
+		}
+	case *d2ast.SingleQuotedString:
+		return value.Value, true
+	}
+	return "", false
+}
+
+// boardAt returns the map of the innermost board holding position, along with the
+// key declaring it, which is nil for the root board.
+func boardAt(m *d2ast.Map, position lsp.Position) (*d2ast.Map, *d2ast.Key) {
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == nil || !isPlainKey(key) || len(key.Key.Path) != 1 || key.Value.Map == nil {
+			continue
+		}
+		if _, ok := d2ast.BoardKeywords[key.Key.Path[0].Unbox().ScalarString()]; !ok {
+			continue
+		}
+		for _, board := range key.Value.Map.Nodes {
+			if board.MapKey != nil && board.MapKey.Value.Map != nil && rangeContains(board.MapKey.Value.Map.Range, position) {
+				if inner, innerKey := boardAt(board.MapKey.Value.Map, position); innerKey != nil {
+					return inner, innerKey
+				}
+				return board.MapKey.Value.Map, board.MapKey
+			}
+		}
+	}
+	return m, nil
+}
+
+// boardLiterals returns the string values set in m. Nested boards and vars are
+// skipped, as are class names since they refer to classes rather than hold text.
+func boardLiterals(m *d2ast.Map) []literal {
+	literals := []literal{}
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == nil {
+			continue
+		}
+		if key.Key != nil && len(key.Key.Path) > 0 {
+			first := key.Key.Path[0].Unbox().ScalarString()
+			if _, ok := d2ast.BoardKeywords[first]; ok || first == "vars" {
+				continue
+			}
+			if key.Key.Last().Unbox().ScalarString() == "class" {
+				continue
+			}
+		}
+		for _, value := range []d2ast.Scalar{key.Primary.Unbox(), key.Value.ScalarBox().Unbox()} {
+			if _, ok := literalString(value); ok {
+				literals = append(literals, literal{value: value, key: key, attribute: literalAttribute(key, value)})
+			}
+		}
+		if key.Value.Map != nil {
+			literals = append(literals, boardLiterals(key.Value.Map)...)
+		}
+	}
+	return literals
+}
+
+// variableName returns a name for the variable holding a value of attribute. A
+// number is appended when vars already declares the name.
+func variableName(attribute string, vars *d2ast.Key) string {
+	name := attribute
+
+	taken := func(name string) bool {
+		if vars == nil {
+			return false
+		}
+		for _, node := range vars.Value.Map.Nodes {
+			if node.MapKey != nil && node.MapKey.Key != nil && len(node.MapKey.Key.Path) > 0 &&
+				node.MapKey.Key.Path[0].Unbox().ScalarString() == name {
+				return true
+			}
+		}
+		return false
+	}
+	base := name
+	for i := 2; taken(name); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+// extractVariable moves the selected literal into a variable of its board and
+// substitutes it wherever the board sets the same literal on the same attribute.
+func (s *State) extractVariable(uri lsp.DocumentURI, rng lsp.Range) *lsp.CodeAction {
+	if rng.Start == rng.End {
+		return nil
+	}
+	text := s.Documents[uri].Text
+	root := freshAST(uri, text)
+	if root == nil {
+		return nil
+	}
+	board, boardKey := boardAt(root, rng.Start)
+
+	literals := boardLiterals(board)
+	var selected *literal
+	for i, l := range literals {
+		r := l.value.GetRange()
+		if rangeContains(r, rng.Start) && rangeContains(r, rng.End) {
+			selected = &literals[i]
+		}
+	}
+	if selected == nil {
+		return nil
+	}
+	value, _ := literalString(selected.value)
+
+	vars := siblingMap(board, d2ast.FlatUnquotedString("vars"), nil)
+	name := variableName(selected.attribute, vars)
+	variable := &d2ast.Key{
+		Key:   &d2ast.KeyPath{Path: []*d2ast.StringBox{d2ast.RawStringBox(name, true)}},
+		Value: d2ast.MakeValueBox(selected.value),
+	}
+
+	edits := []lsp.TextEdit{}
+	if vars != nil {
+		mergeKey(vars.Value.Map, variable)
+		edits = append(edits, lsp.TextEdit{
+			Range:   toLspRange(vars.Range),
+			NewText: formatAt(text, vars),
+		})
+	} else {
+		vars = &d2ast.Key{
+			Key:   &d2ast.KeyPath{Path: []*d2ast.StringBox{d2ast.RawStringBox("vars", true)}},
+			Value: d2ast.MakeValueBox(lineMap(1)),
+		}
+		mergeKey(vars.Value.Map, variable)
+		// Vars go at the top of the board so they read before being used.
+		var position lsp.Position
+		indent := ""
+		if boardKey != nil {
+			first := board.Nodes[0].Unbox().GetRange().Start
+			if first.Line == board.Range.Start.Line {
+				// The board is written on a single line, with no room for a map.
+				return nil
+			}
+			line := lineAt(text, first.Line)
+			indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			position = lsp.Position{Line: first.Line}
+		}
+		edits = append(edits, lsp.TextEdit{
+			Range:   lsp.Range{Start: position, End: position},
+			NewText: indent + indentLines(d2format.Format(vars), indent) + "\n\n",
+		})
+	}
+
+	substitution := "${" + name + "}"
+	for _, l := range literals {
+		if other, _ := literalString(l.value); other == value && l.attribute == selected.attribute {
+			edits = append(edits, lsp.TextEdit{
+				Range:   toLspRange(l.value.GetRange()),
+				NewText: substitution,
+			})
+		}
+	}
+
+	slices.SortFunc(edits, func(a, b lsp.TextEdit) int {
+		return comparePositions(a.Range.Start, b.Range.Start)
+	})
+
+	return &lsp.CodeAction{
+		Title: fmt.Sprintf("Extract %s into variable %s", d2format.Format(selected.value), name),
+		Kind:  lsp.RefactorExtract,
+		Edit: &lsp.WorkspaceEdit{
+			Changes: map[lsp.DocumentURI][]lsp.TextEdit{uri: edits},
+		},
+	}
+}
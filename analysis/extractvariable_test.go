@@ -0,0 +1,104 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestExtractVariable(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		rng   lsp.Range
+		title string
+		want  string
+	}{
+		{
+			name: "colour",
+			text: "a.style.fill: red\nb: {\n  style.fill: red\n  label: red\n}\nc.style.fill: blue\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 14},
+				End:   lsp.Position{Line: 0, Character: 17},
+			},
+			title: "Extract red into variable fill",
+			want:  "vars: {\n  fill: red\n}\n\na.style.fill: ${fill}\nb: {\n  style.fill: ${fill}\n  label: red\n}\nc.style.fill: blue\n",
+		},
+		{
+			name: "label keeps other attributes",
+			text: "a: hi\nb.shape: hi\nc.label: hi\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 3},
+				End:   lsp.Position{Line: 0, Character: 5},
+			},
+			title: "Extract hi into variable label",
+			want:  "vars: {\n  label: hi\n}\n\na: ${label}\nb.shape: hi\nc.label: ${label}\n",
+		},
+		{
+			name: "label with existing vars",
+			text: "vars: {\n  label: x\n}\na: \"Hello world\"\nb -> a: \"Hello world\"\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 3, Character: 4},
+				End:   lsp.Position{Line: 3, Character: 9},
+			},
+			title: "Extract \"Hello world\" into variable label-2",
+			want:  "vars: {\n  label: x\n  label-2: \"Hello world\"\n}\na: ${label-2}\nb -> a: ${label-2}\n",
+		},
+		{
+			name: "nested board",
+			text: "layers: {\n  x: {\n    a: Hi\n    b: Hi\n  }\n}\nc: Hi\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 2, Character: 7},
+				End:   lsp.Position{Line: 2, Character: 9},
+			},
+			title: "Extract Hi into variable label",
+			want:  "layers: {\n  x: {\n    vars: {\n      label: Hi\n    }\n\n    a: ${label}\n    b: ${label}\n  }\n}\nc: Hi\n",
+		},
+		{
+			name: "cursor",
+			text: "a: Hi\nb: Hi\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 4},
+				End:   lsp.Position{Line: 0, Character: 4},
+			},
+		},
+		{
+			name: "substitution",
+			text: "vars: {x: Hi}\na: ${x}\n",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 3},
+				End:   lsp.Position{Line: 1, Character: 7},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			uri := openTestDocument(t, state, "test.d2", test.text)
+
+			context := lsp.CodeActionContext{Only: []lsp.CodeActionKind{lsp.RefactorExtract}}
+			response := state.CodeActions(1, uri, test.rng, context)
+			var action *lsp.CodeAction
+			for i := range response.Result {
+				if response.Result[i].Title == test.title {
+					action = &response.Result[i]
+				}
+			}
+			if test.title == "" {
+				if len(response.Result) != 0 {
+					t.Fatalf("CodeActions() = %+v, want none", response.Result)
+				}
+				return
+			}
+			if action == nil {
+				t.Fatalf("CodeActions() = %+v, want %q", response.Result, test.title)
+			}
+			got := applyEdits(test.text, action.Edit.Changes[uri])
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("extracted document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
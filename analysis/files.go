@@ -0,0 +1,176 @@
+package analysis
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// movedPath returns where p ends up once the file or directory at oldPath is moved
+// to newPath.
+func movedPath(p, oldPath, newPath string) string {
+	if p == oldPath {
+		return newPath
+	}
+	if rest, ok := strings.CutPrefix(p, oldPath+"/"); ok {
+		return path.Join(newPath, rest)
+	}
+	return p
+}
+
+// retargetImport returns a copy of imp pointing at target when imported from
+// importer, or nil if target can't be reached with a relative path.
+func retargetImport(imp *d2ast.Import, importer, target string) *d2ast.Import {
+	impPath := target
+	if !filepath.IsAbs(imp.PathWithPre()) {
+		rel, err := filepath.Rel(path.Dir(importer), target)
+		if err != nil {
+			return nil
+		}
+		impPath = filepath.ToSlash(rel)
+	}
+	// Keep the extension only if it was written out.
+	if path.Ext(imp.PathWithPre()) != ".d2" {
+		impPath = strings.TrimSuffix(impPath, ".d2")
+	}
+
+	// Parent directories go in front of the path like the parser puts them, the
+	// formatter would quote them otherwise.
+	pre := ""
+	for strings.HasPrefix(impPath, "../") {
+		pre += "../"
+		impPath = strings.TrimPrefix(impPath, "../")
+	}
+
+	retargeted := *imp
+	retargeted.Pre = pre
+	retargeted.Path = append([]*d2ast.StringBox{d2ast.RawStringBox(impPath, true)}, imp.Path[1:]...)
+	return &retargeted
+}
+
+// rewriteImport returns imp pointing at target when imported from importer.
+func rewriteImport(imp *d2ast.Import, importer, target string) string {
+	retargeted := retargetImport(imp, importer, target)
+	if retargeted == nil {
+		return ""
+	}
+	return d2format.Format(retargeted)
+}
+
+func (s *State) WillRenameFiles(id any, files []lsp.FileRename) lsp.WillRenameFilesResponse {
+	edit := &lsp.WorkspaceEdit{
+		Changes: map[lsp.DocumentURI][]lsp.TextEdit{},
+	}
+	moved := func(p string) string {
+		for _, file := range files {
+			p = movedPath(p, file.OldURI.Filename(), file.NewURI.Filename())
+		}
+		return p
+	}
+
+	for _, importer := range s.knownFiles() {
+		text, err := s.fileText(importer)
+		if err != nil {
+			s.logger.Printf("could not read %s: %v", importer, err)
+			continue
+		}
+		ast, _ := d2parser.Parse(importer, strings.NewReader(text), &d2parser.ParseOptions{
+			UTF16Pos: true,
+		})
+		if ast == nil {
+			continue
+		}
+
+		// Moving the importer itself breaks its relative imports as well.
+		newImporter := moved(importer)
+		d2ast.Walk(ast, func(node d2ast.Node) bool {
+			imp, ok := node.(*d2ast.Import)
+			if !ok {
+				return true
+			}
+			target := importPath(importer, imp)
+			newTarget := moved(target)
+			if newTarget == target && newImporter == importer {
+				return false
+			}
+			rewritten := rewriteImport(imp, newImporter, newTarget)
+			if rewritten == "" || rewritten == d2format.Format(imp) {
+				return false
+			}
+
+			uri := lsp.File(importer)
+			edit.Changes[uri] = append(edit.Changes[uri], lsp.TextEdit{
+				Range:   toLspRange(imp.Range),
+				NewText: rewritten,
+			})
+			return false
+		})
+	}
+
+	return lsp.WillRenameFilesResponse{
+		Response: lsp.NewResponse(id),
+		Result:   edit,
+	}
+}
+
+// d2Files returns the D2 files at path, which may be a single file or a directory.
+func d2Files(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if info.IsDir() {
+		return findFilesByExt(path, ".d2")
+	}
+	if filepath.Ext(path) == ".d2" {
+		return []string{path}
+	}
+	return nil
+}
+
+// workspaceFilesUnder returns the workspace files at path or, if it was a directory,
+// inside it.
+func (s *State) workspaceFilesUnder(path string) []string {
+	var files []string
+	for _, workspace := range s.WorkspaceFolders {
+		for _, file := range workspace.Files {
+			if file == path || strings.HasPrefix(file, path+string(filepath.Separator)) {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+func (s *State) CreateFiles(files []lsp.FileCreate) {
+	for _, file := range files {
+		for _, path := range d2Files(file.URI.Filename()) {
+			s.UpdateFile(path, lsp.Created)
+		}
+	}
+}
+
+func (s *State) DeleteFiles(files []lsp.FileDelete) {
+	for _, file := range files {
+		for _, path := range s.workspaceFilesUnder(file.URI.Filename()) {
+			s.UpdateFile(path, lsp.Deleted)
+		}
+	}
+}
+
+func (s *State) RenameFiles(files []lsp.FileRename) {
+	for _, file := range files {
+		for _, path := range s.workspaceFilesUnder(file.OldURI.Filename()) {
+			s.UpdateFile(path, lsp.Deleted)
+		}
+		for _, path := range d2Files(file.NewURI.Filename()) {
+			s.UpdateFile(path, lsp.Created)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package analysis_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestWillRenameFiles(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"main.d2":          "...@shared/styles\nu: @models.user\n",
+		"models.d2":        "user\n",
+		"shared/styles.d2": "...@../models\n",
+	})
+
+	tests := []struct {
+		name    string
+		renames map[string]string
+		want    []string
+	}{
+		{
+			name:    "file",
+			renames: map[string]string{"models.d2": "db/models.d2"},
+			want: []string{
+				"main.d2:2:4 @db/models.user",
+				"styles.d2:1:1 ...@../db/models",
+			},
+		},
+		{
+			name:    "directory",
+			renames: map[string]string{"shared": "lib/common"},
+			want: []string{
+				"main.d2:1:1 ...@lib/common/styles",
+				"styles.d2:1:1 ...@../../models",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			state.AddWorkspaceFolders([]lsp.WorkspaceFolder{{URI: lsp.File(root), Name: "test"}})
+
+			files := []lsp.FileRename{}
+			for oldPath, newPath := range test.renames {
+				files = append(files, lsp.FileRename{
+					OldURI: lsp.File(filepath.Join(root, oldPath)),
+					NewURI: lsp.File(filepath.Join(root, newPath)),
+				})
+			}
+			response := state.WillRenameFiles(1, files)
+
+			got := []string{}
+			for uri, edits := range response.Result.Changes {
+				for _, edit := range edits {
+					got = append(got, fmt.Sprintf(
+						"%s:%d:%d %s",
+						filepath.Base(uri.Filename()),
+						edit.Range.Start.Line+1,
+						edit.Range.Start.Character+1,
+						edit.NewText,
+					))
+				}
+			}
+			slices.Sort(got)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("WillRenameFiles() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFileOperations(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"a.d2":     "a\n",
+		"dir/b.d2": "b\n",
+	})
+	folder := lsp.File(root)
+	state := newTestState(t)
+	state.AddWorkspaceFolders([]lsp.WorkspaceFolder{{URI: folder, Name: "test"}})
+
+	files := func() []string {
+		files := []string{}
+		for _, file := range state.WorkspaceFolders[folder].Files {
+			rel, _ := filepath.Rel(root, file)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		slices.Sort(files)
+		return files
+	}
+
+	writeTestFilesIn(t, root, map[string]string{"c.d2": "c\n"})
+	state.CreateFiles([]lsp.FileCreate{{URI: lsp.File(filepath.Join(root, "c.d2"))}})
+	state.CreateFiles([]lsp.FileCreate{{URI: lsp.File(filepath.Join(root, "c.d2"))}})
+	if diff := cmp.Diff([]string{"a.d2", "c.d2", "dir/b.d2"}, files()); diff != "" {
+		t.Errorf("CreateFiles() mismatch (-want +got):\n%s", diff)
+	}
+
+	writeTestFilesIn(t, root, map[string]string{"moved/b.d2": "b\n"})
+	state.RenameFiles([]lsp.FileRename{{
+		OldURI: lsp.File(filepath.Join(root, "dir")),
+		NewURI: lsp.File(filepath.Join(root, "moved")),
+	}})
+	if diff := cmp.Diff([]string{"a.d2", "c.d2", "moved/b.d2"}, files()); diff != "" {
+		t.Errorf("RenameFiles() mismatch (-want +got):\n%s", diff)
+	}
+
+	state.DeleteFiles([]lsp.FileDelete{{URI: lsp.File(filepath.Join(root, "moved"))}})
+	if diff := cmp.Diff([]string{"a.d2", "c.d2"}, files()); diff != "" {
+		t.Errorf("DeleteFiles() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,70 @@
+package analysis
+
+import (
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+)
+
+// foldingRanges returns the regions of node and everything inside it. Maps, arrays
+// and block strings fold up to their closing line so it stays visible.
+func foldingRanges(node d2ast.Node) []lsp.FoldingRange {
+	if node == nil {
+		return nil
+	}
+	r := node.GetRange()
+	region := func(kind lsp.FoldingRangeKind, endLine int) []lsp.FoldingRange {
+		if endLine <= r.Start.Line {
+			return nil
+		}
+		return []lsp.FoldingRange{{StartLine: r.Start.Line, EndLine: endLine, Kind: kind}}
+	}
+
+	switch node := node.(type) {
+	case *d2ast.Map:
+		ranges := []lsp.FoldingRange{}
+		if !node.IsFileMap() {
+			ranges = append(ranges, region(lsp.FoldingRangeKindRegion, r.End.Line-1)...)
+		}
+		for _, child := range node.Nodes {
+			ranges = append(ranges, foldingRanges(child.Unbox())...)
+		}
+		return ranges
+	case *d2ast.Array:
+		ranges := region(lsp.FoldingRangeKindRegion, r.End.Line-1)
+		for _, child := range node.Nodes {
+			ranges = append(ranges, foldingRanges(child.Unbox())...)
+		}
+		return ranges
+	case *d2ast.Key:
+		ranges := []lsp.FoldingRange{}
+		if primary := node.Primary.Unbox(); primary != nil {
+			ranges = append(ranges, foldingRanges(primary)...)
+		}
+		if value := node.Value.Unbox(); value != nil {
+			ranges = append(ranges, foldingRanges(value)...)
+		}
+		return ranges
+	case *d2ast.BlockString:
+		return region(lsp.FoldingRangeKindRegion, r.End.Line-1)
+	case *d2ast.Comment, *d2ast.BlockComment:
+		// Consecutive comment lines are parsed as a single comment.
+		return region(lsp.FoldingRangeKindComment, r.End.Line)
+	}
+	return nil
+}
+
+func (s *State) FoldingRanges(id any, uri lsp.DocumentURI) lsp.FoldingRangeResponse {
+	response := lsp.FoldingRangeResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.FoldingRange{},
+	}
+
+	// The AST is kept even when the document has syntax errors, so most regions can
+	// still be folded.
+	document := s.Documents[uri]
+	if document.AST == nil {
+		return response
+	}
+	response.Result = append(response.Result, foldingRanges(document.AST)...)
+	return response
+}
@@ -0,0 +1,46 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestFoldingRanges(t *testing.T) {
+	state := newTestState(t)
+	uri := openTestDocument(t, state, "test.d2", `# Services
+# and their stores
+
+backend: {
+  api: {shape: hexagon}
+  db: {
+    shape: cylinder
+  }
+}
+notes: |md
+  # Notes
+  More
+|
+"""
+Block comment
+"""
+list: [
+  1
+  2
+]
+`)
+
+	want := []lsp.FoldingRange{
+		{StartLine: 0, EndLine: 1, Kind: lsp.FoldingRangeKindComment},
+		{StartLine: 3, EndLine: 7, Kind: lsp.FoldingRangeKindRegion},
+		{StartLine: 5, EndLine: 6, Kind: lsp.FoldingRangeKindRegion},
+		{StartLine: 9, EndLine: 11, Kind: lsp.FoldingRangeKindRegion},
+		{StartLine: 13, EndLine: 15, Kind: lsp.FoldingRangeKindComment},
+		{StartLine: 16, EndLine: 18, Kind: lsp.FoldingRangeKindRegion},
+	}
+	got := state.FoldingRanges(1, uri).Result
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FoldingRanges() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,338 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2ir"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// objectID returns the absolute key of field, including the path of its board.
+func objectID(field *d2ir.Field) string {
+	// The first element is the name of the root field.
+	return formatIDA(d2ir.IDA(field)[1:])
+}
+
+func objectByID(ir *d2ir.Map, id string) *d2ir.Field {
+	keyPath, err := d2parser.ParseKey(id)
+	if err != nil {
+		return nil
+	}
+	return ir.GetField(keyPath.IDA()...)
+}
+
+// boardPath returns the absolute key of the board node belongs to, empty for the
+// root board.
+func boardPath(node d2ir.Node) string {
+	board := boardRoot(node)
+	if board.Root() {
+		return ""
+	}
+	return objectID(board.Parent().(*d2ir.Field))
+}
+
+func symbolKind(field *d2ir.Field) lsp.SymbolKind {
+	if d2ir.NodeBoardKind(field) != "" {
+		return lsp.SymbolKindModule
+	}
+	if field.Map() != nil {
+		if shape := field.Map().GetField(d2ast.FlatUnquotedString("shape")); shape != nil && shape.Primary() != nil {
+			switch shape.Primary().Value.ScalarString() {
+			case "sql_table":
+				return lsp.SymbolKindStruct
+			case "class":
+				return lsp.SymbolKindClass
+			case "image", "page", "document":
+				return lsp.SymbolKindFile
+			case "text", "code":
+				return lsp.SymbolKindString
+			case "queue":
+				return lsp.SymbolKindArray
+			}
+		}
+	}
+	if isContainer(field) {
+		return lsp.SymbolKindNamespace
+	}
+	return lsp.SymbolKindObject
+}
+
+// objectItem describes field as declared first.
+func objectItem(uri lsp.DocumentURI, field *d2ir.Field) lsp.CallHierarchyItem {
+	ref := field.References[0]
+	selection := ref.String.GetRange()
+	r := selection
+	if ref.Context_.Key != nil {
+		r = ref.Context_.Key.Range
+	}
+
+	return lsp.CallHierarchyItem{
+		Name:           formatIDA(d2ir.BoardIDA(field)),
+		Kind:           symbolKind(field),
+		Detail:         boardPath(field),
+		URI:            lsp.File(selection.Path),
+		Range:          toLspRange(r),
+		SelectionRange: toLspRange(selection),
+		Data: lsp.SymbolData{
+			URI: uri,
+			ID:  objectID(field),
+		},
+	}
+}
+
+// connection is a single direction of an edge, undirected and bidirectional edges
+// flow both ways.
+type connection struct {
+	From *d2ir.Field
+	To   *d2ir.Field
+	Edge *d2ir.Edge
+}
+
+// connections returns the connections in the board of field that start or end at it.
+func connections(field *d2ir.Field) []connection {
+	board := boardRoot(field)
+	var found []connection
+	walkIR(board, func(node d2ir.Node) {
+		edge, ok := node.(*d2ir.Edge)
+		if !ok || boardRoot(edge) != board {
+			return
+		}
+		m := d2ir.ParentMap(edge)
+		src := m.GetField(edge.ID.SrcPath...)
+		dst := m.GetField(edge.ID.DstPath...)
+		if src == nil || dst == nil || (src != field && dst != field) {
+			return
+		}
+		if edge.ID.DstArrow || !edge.ID.SrcArrow {
+			found = append(found, connection{From: src, To: dst, Edge: edge})
+		}
+		if edge.ID.SrcArrow || !edge.ID.DstArrow {
+			found = append(found, connection{From: dst, To: src, Edge: edge})
+		}
+	})
+	return found
+}
+
+// edgeRanges returns where edge is written in the file at path.
+func edgeRanges(edge *d2ir.Edge, path string) []lsp.Range {
+	ranges := []lsp.Range{}
+	for _, ref := range edge.References {
+		r := ref.Context_.Edge.Range
+		if r.Path == path && isHomeRef(edge, ref.Context_) {
+			ranges = append(ranges, toLspRange(r))
+		}
+	}
+	return ranges
+}
+
+func edgeLabel(edge *d2ir.Edge) string {
+	if edge.Primary() != nil {
+		return edge.Primary().Value.ScalarString()
+	}
+	if edge.Map() != nil {
+		if label := edge.Map().GetField(d2ast.FlatUnquotedString("label")); label != nil && label.Primary() != nil {
+			return label.Primary().Value.ScalarString()
+		}
+	}
+	return ""
+}
+
+// calls groups the connections of the object item refers to by the object at their
+// other end. Their edges are the call sites, labelled in the detail of the item.
+func (s *State) calls(item lsp.CallHierarchyItem, incoming bool) ([]lsp.CallHierarchyItem, [][]lsp.Range) {
+	_, ir, err := s.compileDocument(item.Data.URI)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", item.Data.URI, err)
+		return nil, nil
+	}
+	field := objectByID(ir, item.Data.ID)
+	if field == nil {
+		return nil, nil
+	}
+
+	path := item.Data.URI.Filename()
+	var items []lsp.CallHierarchyItem
+	var ranges [][]lsp.Range
+	var labels [][]string
+	index := map[*d2ir.Field]int{}
+	for _, c := range connections(field) {
+		other := c.To
+		if incoming {
+			if c.To != field {
+				continue
+			}
+			other = c.From
+		} else if c.From != field {
+			continue
+		}
+
+		i, ok := index[other]
+		if !ok {
+			i = len(items)
+			index[other] = i
+			items = append(items, objectItem(item.Data.URI, other))
+			ranges = append(ranges, []lsp.Range{})
+			labels = append(labels, nil)
+		}
+		ranges[i] = append(ranges[i], edgeRanges(c.Edge, path)...)
+		if label := edgeLabel(c.Edge); label != "" {
+			labels[i] = append(labels[i], label)
+		}
+	}
+	for i := range items {
+		if len(labels[i]) > 0 {
+			items[i].Detail = strings.Join(labels[i], ", ")
+		}
+	}
+
+	return items, ranges
+}
+
+func (s *State) PrepareCallHierarchy(id any, uri lsp.DocumentURI, position lsp.Position) lsp.CallHierarchyPrepareResponse {
+	response := lsp.CallHierarchyPrepareResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.CallHierarchyItem{},
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+		return response
+	}
+	if target := referenceTarget(ast, ir, position); target != nil && d2ir.NodeBoardKind(target) == "" {
+		response.Result = append(response.Result, objectItem(uri, target))
+	}
+
+	return response
+}
+
+func (s *State) IncomingCalls(id any, item lsp.CallHierarchyItem) lsp.CallHierarchyIncomingCallsResponse {
+	response := lsp.CallHierarchyIncomingCallsResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.CallHierarchyIncomingCall{},
+	}
+
+	items, ranges := s.calls(item, true)
+	for i, from := range items {
+		response.Result = append(response.Result, lsp.CallHierarchyIncomingCall{
+			From:       from,
+			FromRanges: ranges[i],
+		})
+	}
+
+	return response
+}
+
+func (s *State) OutgoingCalls(id any, item lsp.CallHierarchyItem) lsp.CallHierarchyOutgoingCallsResponse {
+	response := lsp.CallHierarchyOutgoingCallsResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.CallHierarchyOutgoingCall{},
+	}
+
+	items, ranges := s.calls(item, false)
+	for i, to := range items {
+		response.Result = append(response.Result, lsp.CallHierarchyOutgoingCall{
+			To:         to,
+			FromRanges: ranges[i],
+		})
+	}
+
+	return response
+}
+
+// parentObject returns the container or board holding field, nil at the top of the
+// root board.
+func parentObject(field *d2ir.Field) *d2ir.Field {
+	for {
+		parent, ok := d2ir.ParentMap(field).Parent().(*d2ir.Field)
+		if !ok || parent.Root() {
+			return nil
+		}
+		// Boards sit below a layers, scenarios or steps keyword.
+		if !isReservedField(parent) {
+			return parent
+		}
+		field = parent
+	}
+}
+
+// childObjects returns the shapes in field, and the boards below it when field is a
+// board.
+func childObjects(field *d2ir.Field) []*d2ir.Field {
+	var children []*d2ir.Field
+	if field.Map() == nil {
+		return children
+	}
+	for _, child := range field.Map().Fields {
+		if !isReservedField(child) {
+			children = append(children, child)
+			continue
+		}
+		if d2ir.NodeBoardKind(field) == "" || child.Map() == nil {
+			continue
+		}
+		switch child.Name.ScalarString() {
+		case "layers", "scenarios", "steps":
+			children = append(children, child.Map().Fields...)
+		}
+	}
+	return children
+}
+
+// typeItems resolves item back to its object and describes the objects related to it.
+func (s *State) typeItems(item lsp.TypeHierarchyItem, related func(*d2ir.Field) []*d2ir.Field) []lsp.TypeHierarchyItem {
+	items := []lsp.TypeHierarchyItem{}
+	_, ir, err := s.compileDocument(item.Data.URI)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", item.Data.URI, err)
+		return items
+	}
+	field := objectByID(ir, item.Data.ID)
+	if field == nil {
+		return items
+	}
+
+	for _, other := range related(field) {
+		items = append(items, lsp.TypeHierarchyItem(objectItem(item.Data.URI, other)))
+	}
+	return items
+}
+
+func (s *State) PrepareTypeHierarchy(id any, uri lsp.DocumentURI, position lsp.Position) lsp.TypeHierarchyPrepareResponse {
+	response := lsp.TypeHierarchyPrepareResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.TypeHierarchyItem{},
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+		return response
+	}
+	if target := referenceTarget(ast, ir, position); target != nil {
+		response.Result = append(response.Result, lsp.TypeHierarchyItem(objectItem(uri, target)))
+	}
+
+	return response
+}
+
+func (s *State) Supertypes(id any, item lsp.TypeHierarchyItem) lsp.TypeHierarchyResponse {
+	return lsp.TypeHierarchyResponse{
+		Response: lsp.NewResponse(id),
+		Result: s.typeItems(item, func(field *d2ir.Field) []*d2ir.Field {
+			if parent := parentObject(field); parent != nil {
+				return []*d2ir.Field{parent}
+			}
+			return nil
+		}),
+	}
+}
+
+func (s *State) Subtypes(id any, item lsp.TypeHierarchyItem) lsp.TypeHierarchyResponse {
+	return lsp.TypeHierarchyResponse{
+		Response: lsp.NewResponse(id),
+		Result:   s.typeItems(item, childObjects),
+	}
+}
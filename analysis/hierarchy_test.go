@@ -0,0 +1,108 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestCallHierarchy(t *testing.T) {
+	state := newTestState(t)
+	text := "api -> db: queries\napi -> db: writes\nweb -> api\nx: {\n  worker -> _.db\n}\ncache <-> api\nlayers: {\n  l: {\n    other -> api\n  }\n}\n"
+	uri := openTestDocument(t, state, "test.d2", text)
+
+	prepare := state.PrepareCallHierarchy(1, uri, lsp.Position{Line: 0, Character: 1})
+	if len(prepare.Result) != 1 {
+		t.Fatalf("PrepareCallHierarchy() = %v, want one item", prepare.Result)
+	}
+	item := prepare.Result[0]
+	if item.Name != "api" || item.Data.ID != "api" {
+		t.Errorf("PrepareCallHierarchy() = %+v, want api", item)
+	}
+
+	type call struct {
+		Name   string
+		Detail string
+		Lines  []int
+	}
+
+	incoming := []call{}
+	for _, c := range state.IncomingCalls(2, item).Result {
+		lines := []int{}
+		for _, r := range c.FromRanges {
+			lines = append(lines, r.Start.Line)
+		}
+		incoming = append(incoming, call{c.From.Name, c.From.Detail, lines})
+	}
+	wantIncoming := []call{
+		{Name: "web", Lines: []int{2}},
+		{Name: "cache", Lines: []int{6}},
+	}
+	if diff := cmp.Diff(wantIncoming, incoming); diff != "" {
+		t.Errorf("IncomingCalls() mismatch (-want +got):\n%s", diff)
+	}
+
+	outgoing := []call{}
+	for _, c := range state.OutgoingCalls(3, item).Result {
+		lines := []int{}
+		for _, r := range c.FromRanges {
+			lines = append(lines, r.Start.Line)
+		}
+		outgoing = append(outgoing, call{c.To.Name, c.To.Detail, lines})
+	}
+	wantOutgoing := []call{
+		{Name: "db", Detail: "queries, writes", Lines: []int{0, 1}},
+		{Name: "cache", Lines: []int{6}},
+	}
+	if diff := cmp.Diff(wantOutgoing, outgoing); diff != "" {
+		t.Errorf("OutgoingCalls() mismatch (-want +got):\n%s", diff)
+	}
+
+	db := state.IncomingCalls(4, state.OutgoingCalls(3, item).Result[0].To).Result
+	names := []string{}
+	for _, c := range db {
+		names = append(names, c.From.Name)
+	}
+	if diff := cmp.Diff([]string{"api", "x.worker"}, names); diff != "" {
+		t.Errorf("IncomingCalls(db) mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTypeHierarchy(t *testing.T) {
+	state := newTestState(t)
+	text := "cloud: {\n  vpc: {\n    api\n    db\n  }\n  style.fill: blue\n}\nlayers: {\n  l: {\n    q\n  }\n}\n"
+	uri := openTestDocument(t, state, "test.d2", text)
+
+	prepare := state.PrepareTypeHierarchy(1, uri, lsp.Position{Line: 1, Character: 3})
+	if len(prepare.Result) != 1 {
+		t.Fatalf("PrepareTypeHierarchy() = %v, want one item", prepare.Result)
+	}
+	vpc := prepare.Result[0]
+
+	names := func(items []lsp.TypeHierarchyItem) []string {
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Data.ID)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name string
+		got  []lsp.TypeHierarchyItem
+		want []string
+	}{
+		{"supertypes", state.Supertypes(2, vpc).Result, []string{"cloud"}},
+		{"subtypes", state.Subtypes(3, vpc).Result, []string{"cloud.vpc.api", "cloud.vpc.db"}},
+		{"top level", state.Supertypes(4, state.Supertypes(2, vpc).Result[0]).Result, []string{}},
+		{"board shapes", state.Supertypes(5, state.PrepareTypeHierarchy(6, uri, lsp.Position{Line: 9, Character: 4}).Result[0]).Result, []string{"layers.l"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, names(test.got)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
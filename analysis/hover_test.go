@@ -0,0 +1,87 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestHoverThumbnail(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		position lsp.Position
+		expected bool
+	}{
+		{
+			name:     "container",
+			text:     "backend: {\n  api -> db\n}\n",
+			position: lsp.Position{Line: 0, Character: 2},
+			expected: true,
+		},
+		{
+			name:     "board",
+			text:     "layers: {\n  x: {\n    a -> b\n  }\n}\n",
+			position: lsp.Position{Line: 1, Character: 2},
+			expected: true,
+		},
+		{
+			name:     "shape",
+			text:     "a -> b\n",
+			position: lsp.Position{Line: 0, Character: 0},
+			expected: false,
+		},
+		{
+			name:     "whitespace",
+			text:     "a -> b\n\n",
+			position: lsp.Position{Line: 1, Character: 0},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			uri := openTestDocument(t, state, "test.d2", test.text)
+
+			response := state.Hover(1, uri, test.position)
+			actual := response.Result != nil &&
+				strings.Contains(response.Result.Contents.Value, "data:image/svg+xml;base64,")
+			if actual != test.expected {
+				t.Errorf("Hover(%v) thumbnail = %v, want %v", test.position, actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestHoverThumbnailImportChanged(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{"lib.d2": "a -> b\n"})
+	uri := lsp.File(filepath.Join(root, "main.d2"))
+	state := newTestState(t)
+	state.OpenDocument(uri, 1, "x: @lib\n")
+
+	position := lsp.Position{Line: 0, Character: 0}
+	hover := func() string {
+		response := state.Hover(1, uri, position)
+		if response.Result == nil {
+			t.Fatalf("Hover(%v) returned no result", position)
+		}
+		return response.Result.Contents.Value
+	}
+	before := hover()
+	if hover() != before {
+		t.Errorf("Hover(%v) changed without any edit", position)
+	}
+
+	writeTestFilesIn(t, root, map[string]string{"lib.d2": "a -> b -> c\n"})
+	if hover() == before {
+		t.Errorf("Hover(%v) kept the thumbnail of the previous import", position)
+	}
+
+	closed := lsp.File(filepath.Join(root, "lib.d2"))
+	if response := state.Hover(2, closed, position); response.Result != nil {
+		t.Errorf("Hover() on a closed document = %+v, want no result", response.Result)
+	}
+}
@@ -0,0 +1,132 @@
+package analysis
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// importPath resolves imp the same way D2 does, relative to the importing file.
+func importPath(importer string, imp *d2ast.Import) string {
+	impPath := imp.PathWithPre()
+	if path.Ext(impPath) != ".d2" {
+		impPath += ".d2"
+	}
+	if !filepath.IsAbs(impPath) {
+		impPath = path.Join(path.Dir(importer), impPath)
+	}
+	return impPath
+}
+
+// fileText returns the content of the open document at path, or of the file on disk.
+func (s *State) fileText(path string) (string, error) {
+	if text, ok := s.fileSystem()[path]; ok {
+		return text, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// knownFiles returns the paths of all workspace files and open documents.
+func (s *State) knownFiles() []string {
+	files := []string{}
+	for _, workspace := range s.WorkspaceFolders {
+		files = append(files, workspace.Files...)
+	}
+	for uri := range s.Documents {
+		files = append(files, uri.Filename())
+	}
+	slices.Sort(files)
+	return slices.Compact(files)
+}
+
+// parseFile parses the file at path, keeping what parses of files with syntax
+// errors.
+func (s *State) parseFile(path string) *d2ast.Map {
+	text, err := s.fileText(path)
+	if err != nil {
+		s.logger.Printf("could not read %s: %v", path, err)
+		return nil
+	}
+	ast, _ := d2parser.Parse(path, strings.NewReader(text), &d2parser.ParseOptions{
+		UTF16Pos: true,
+	})
+	return ast
+}
+
+// fileImports returns the paths imported by the file at path.
+func (s *State) fileImports(path string) []string {
+	// Imports are still worth following in files with syntax errors.
+	ast := s.parseFile(path)
+	if ast == nil {
+		return nil
+	}
+
+	imports := []string{}
+	d2ast.Walk(ast, func(node d2ast.Node) bool {
+		if imp, ok := node.(*d2ast.Import); ok {
+			imports = append(imports, importPath(path, imp))
+		}
+		return true
+	})
+	return imports
+}
+
+// importContents returns the text of every file path imports, directly or through
+// other imports, by path. Files that can't be read are left out.
+func (s *State) importContents(path string) map[string]string {
+	contents := map[string]string{}
+	queue := []string{path}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		for _, imported := range s.fileImports(file) {
+			if _, ok := contents[imported]; ok || imported == path {
+				continue
+			}
+			text, err := s.fileText(imported)
+			if err != nil {
+				continue
+			}
+			contents[imported] = text
+			queue = append(queue, imported)
+		}
+	}
+	return contents
+}
+
+// importers returns the known files that import path, directly or through other
+// imports.
+func (s *State) importers(path string) []string {
+	graph := map[string][]string{}
+	for _, file := range s.knownFiles() {
+		for _, imported := range s.fileImports(file) {
+			graph[imported] = append(graph[imported], file)
+		}
+	}
+
+	seen := map[string]struct{}{path: {}}
+	queue := []string{path}
+	importers := []string{}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		for _, importer := range graph[file] {
+			if _, ok := seen[importer]; ok {
+				continue
+			}
+			seen[importer] = struct{}{}
+			importers = append(importers, importer)
+			queue = append(queue, importer)
+		}
+	}
+	return importers
+}
@@ -0,0 +1,95 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// importedContent returns the formatted content of the file imp imports, with its
+// own imports rewritten to resolve from importer.
+func (s *State) importedContent(importer string, imp *d2ast.Import) (string, error) {
+	path := importPath(importer, imp)
+	text, err := s.fileText(path)
+	if err != nil {
+		return "", err
+	}
+	ast, err := d2parser.Parse(path, strings.NewReader(text), &d2parser.ParseOptions{
+		UTF16Pos: true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	d2ast.Walk(ast, func(node d2ast.Node) bool {
+		if nested, ok := node.(*d2ast.Import); ok {
+			if retargeted := retargetImport(nested, importer, importPath(path, nested)); retargeted != nil {
+				*nested = *retargeted
+			}
+		}
+		return true
+	})
+	return strings.TrimSuffix(d2format.Format(ast), "\n"), nil
+}
+
+// indentLines indents every line of text but the first, which continues the line
+// the text is inserted into.
+func indentLines(text, indent string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineImport replaces the import under the cursor with the content of the file
+// it imports. Spread imports are replaced by the content itself, others by a map
+// holding it.
+func (s *State) inlineImport(uri lsp.DocumentURI, position lsp.Position) *lsp.CodeAction {
+	document := s.Documents[uri]
+	var imp *d2ast.Import
+	for _, node := range nodesAtPosition(document.AST, position) {
+		if i, ok := node.(*d2ast.Import); ok {
+			imp = i
+		}
+	}
+	// Partial imports only bring in part of the file.
+	if imp == nil || len(imp.IDA()) > 0 {
+		return nil
+	}
+
+	importer := uri.Filename()
+	content, err := s.importedContent(importer, imp)
+	if err != nil {
+		s.logger.Printf("could not inline %s: %v", importPath(importer, imp), err)
+		return nil
+	}
+
+	r := toLspRange(imp.Range)
+	line := lineAt(document.Text, r.Start.Line)
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	newText := indentLines(content, indent)
+	if !imp.Spread {
+		newText = "{\n" + indent + "  " + indentLines(content, indent+"  ") + "\n" + indent + "}"
+		if content == "" {
+			newText = "{}"
+		}
+	}
+
+	return &lsp.CodeAction{
+		Title: fmt.Sprintf("Inline %s", filepath.Base(importPath(importer, imp))),
+		Kind:  lsp.RefactorInline,
+		Edit: &lsp.WorkspaceEdit{
+			Changes: map[lsp.DocumentURI][]lsp.TextEdit{
+				uri: {{Range: r, NewText: newText}},
+			},
+		},
+	}
+}
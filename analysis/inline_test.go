@@ -0,0 +1,79 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestInlineImport(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"db.d2":             "users\norders: {shape: sql_table}\n",
+		"shared/network.d2": "lb -> app\napp: @icons\n",
+		"shared/icons.d2":   "icon: https://icons.terrastruct.com/aws.svg\n",
+	})
+	uri := lsp.File(filepath.Join(root, "test.d2"))
+
+	tests := []struct {
+		name     string
+		text     string
+		position lsp.Position
+		title    string
+		want     string
+	}{
+		{
+			name:     "value",
+			text:     "backend: {\n  db: @db\n}\n",
+			position: lsp.Position{Line: 1, Character: 7},
+			title:    "Inline db.d2",
+			want:     "backend: {\n  db: {\n    users\n    orders: {shape: sql_table}\n  }\n}\n",
+		},
+		{
+			name:     "spread",
+			text:     "cloud: {\n  ...@shared/network\n}\n",
+			position: lsp.Position{Line: 1, Character: 8},
+			title:    "Inline network.d2",
+			want:     "cloud: {\n  lb -> app\n  app: @shared/icons\n}\n",
+		},
+		{
+			name:     "partial",
+			text:     "orders: @db.orders\n",
+			position: lsp.Position{Line: 0, Character: 10},
+		},
+		{
+			name:     "not an import",
+			text:     "db\n",
+			position: lsp.Position{Line: 0, Character: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			state.OpenDocument(uri, 1, test.text)
+
+			context := lsp.CodeActionContext{Only: []lsp.CodeActionKind{lsp.RefactorInline}}
+			response := state.CodeActions(1, uri, lsp.Range{Start: test.position, End: test.position}, context)
+			if test.title == "" {
+				if len(response.Result) != 0 {
+					t.Fatalf("CodeActions() = %+v, want none", response.Result)
+				}
+				return
+			}
+			if len(response.Result) != 1 {
+				t.Fatalf("CodeActions() returned %d actions, want 1", len(response.Result))
+			}
+
+			action := response.Result[0]
+			if action.Title != test.title {
+				t.Errorf("title = %q, want %q", action.Title, test.title)
+			}
+			got := applyEdits(test.text, action.Edit.Changes[uri])
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("inlined document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package analysis
+
+import (
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2ir"
+)
+
+// walkIR visits every field and edge below m depth-first, including boards.
+func walkIR(m *d2ir.Map, fn func(d2ir.Node)) {
+	if m == nil {
+		return
+	}
+	for _, field := range m.Fields {
+		fn(field)
+		walkIR(field.Map(), fn)
+	}
+	for _, edge := range m.Edges {
+		fn(edge)
+		walkIR(edge.Map(), fn)
+	}
+}
+
+// isHomeRef reports whether a reference was compiled in the board node belongs to.
+// Scenarios and steps copy their base board along with its references, those copies
+// must not shadow the nodes the references were written for.
+func isHomeRef(node d2ir.Node, ctx *d2ir.RefContext) bool {
+	// Imported references lose their scope.
+	if ctx.ScopeMap == nil {
+		return true
+	}
+	return boardRoot(ctx.ScopeMap) == boardRoot(node)
+}
+
+// fieldAtString returns the field that str, a key path segment in the compiled AST,
+// refers to.
+func fieldAtString(ir *d2ir.Map, str d2ast.Node) *d2ir.Field {
+	var match *d2ir.Field
+	walkIR(ir, func(node d2ir.Node) {
+		field, ok := node.(*d2ir.Field)
+		if !ok || match != nil {
+			return
+		}
+		for _, ref := range field.References {
+			if ref.String == str && isHomeRef(field, ref.Context_) {
+				match = field
+				return
+			}
+		}
+	})
+
+	return match
+}
+
+// nodesByKey maps keys in the compiled AST to the field or edge their value is
+// assigned to.
+func nodesByKey(ir *d2ir.Map) map[*d2ast.Key]d2ir.Node {
+	nodes := map[*d2ast.Key]d2ir.Node{}
+	add := func(node d2ir.Node, ref d2ir.Reference) {
+		key := ref.Context().Key
+		if key == nil || !ref.Primary() || !isHomeRef(node, ref.Context()) {
+			return
+		}
+		if _, ok := nodes[key]; !ok {
+			nodes[key] = node
+		}
+	}
+	walkIR(ir, func(node d2ir.Node) {
+		switch node := node.(type) {
+		case *d2ir.Field:
+			for _, ref := range node.References {
+				add(node, ref)
+			}
+		case *d2ir.Edge:
+			for _, ref := range node.References {
+				add(node, ref)
+			}
+		}
+	})
+
+	return nodes
+}
+
+// boardRoot returns the map of the board that node belongs to.
+func boardRoot(node d2ir.Node) *d2ir.Map {
+	if m, ok := node.(*d2ir.Map); ok && (m.Root() || d2ir.NodeBoardKind(m) != "") {
+		return m
+	}
+	board := d2ir.ParentBoard(node)
+	if board == nil {
+		return d2ir.RootMap(d2ir.ParentMap(node))
+	}
+	return board.Map()
+}
+
+func isReservedField(field *d2ir.Field) bool {
+	_, reserved := d2ast.ReservedKeywords[field.Name.ScalarString()]
+	return reserved && field.Name.IsUnquoted()
+}
+
+// isContainer reports whether field holds shapes or connections.
+func isContainer(field *d2ir.Field) bool {
+	m := field.Map()
+	if m == nil || d2ir.NodeBoardKind(field) != "" {
+		return false
+	}
+	if len(m.Edges) > 0 {
+		return true
+	}
+	for _, child := range m.Fields {
+		if !isReservedField(child) {
+			return true
+		}
+	}
+	return false
+}
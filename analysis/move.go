@@ -0,0 +1,210 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2compiler"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2graph"
+	"oss.terrastruct.com/d2/d2ir"
+	"oss.terrastruct.com/d2/d2oracle"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// declaredIn reports whether every mention of field is written in path, D2's oracle
+// can't edit objects that come from imports or globs.
+func declaredIn(field *d2ir.Field, path string) bool {
+	for _, ref := range field.References {
+		if ref.String == nil {
+			continue
+		}
+		if ref.DueToGlob() || ref.String.GetRange().Path != path {
+			return false
+		}
+	}
+	return true
+}
+
+// compileGraph compiles text into the graph D2's oracle edits.
+func (s *State) compileGraph(path, text string) (*d2graph.Graph, error) {
+	g, _, err := d2compiler.Compile(path, strings.NewReader(text), &d2compiler.CompileOptions{
+		UTF16Pos: true,
+		FS:       s.fileSystem(),
+	})
+	return g, err
+}
+
+// moveActions offers to move the object under the cursor into each container next
+// to it, and out of its own container.
+func (s *State) moveActions(uri lsp.DocumentURI, position lsp.Position) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		return actions
+	}
+	target := referenceTarget(ast, ir, position)
+	if target == nil || boardPath(target) != "" || !declaredIn(target, uri.Filename()) {
+		return actions
+	}
+
+	key := objectID(target)
+	name := formatIDA([]d2ast.String{target.Name})
+	move := func(title, newKey string) {
+		actions = append(actions, lsp.CodeAction{
+			Title: title,
+			Kind:  lsp.RefactorMove,
+			Command: &lsp.Command{
+				Title:     title,
+				Command:   lsp.MoveObjectCommand,
+				Arguments: []any{uri, key, newKey},
+			},
+		})
+	}
+
+	for _, sibling := range d2ir.ParentMap(target).Fields {
+		if sibling != target && !isReservedField(sibling) && isContainer(sibling) {
+			container := objectID(sibling)
+			move(fmt.Sprintf("Move %s into %s", name, container), container+"."+name)
+		}
+	}
+	if parent := parentObject(target); parent != nil {
+		newKey := name
+		if grandparent := parentObject(parent); grandparent != nil {
+			newKey = objectID(grandparent) + "." + name
+		}
+		move(fmt.Sprintf("Move %s out of %s", name, objectID(parent)), newKey)
+	}
+
+	return actions
+}
+
+// moveObject moves the object at key to newKey with D2's oracle, which also updates
+// the connections and near attributes of the document. Importers only mention the
+// object by its ID, so those are rewritten in place.
+func (s *State) moveObject(uri lsp.DocumentURI, key, newKey string) (*lsp.WorkspaceEdit, error) {
+	path := uri.Filename()
+	text, err := s.fileText(path)
+	if err != nil {
+		return nil, err
+	}
+	_, ir, err := s.compileFile(path)
+	if err != nil {
+		return nil, err
+	}
+	target := objectByID(ir, key)
+	if target == nil {
+		return nil, fmt.Errorf("%s is not declared in %s", key, path)
+	}
+	g, err := s.compileGraph(path, text)
+	if err != nil {
+		return nil, err
+	}
+	// Taken keys get a number appended.
+	deltas, err := d2oracle.MoveIDDeltas(g, key, newKey, true)
+	if err != nil {
+		return nil, err
+	}
+	formatted := d2format.Format(g.AST)
+	g, err = d2oracle.Move(g, nil, key, newKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := &lsp.WorkspaceEdit{
+		Changes: map[lsp.DocumentURI][]lsp.TextEdit{
+			uri: MergeEdits(text, formatted, d2format.Format(g.AST)),
+		},
+	}
+	if moved, ok := deltas[key]; ok {
+		newKey = moved
+	}
+	newPath, err := d2parser.ParseKey(newKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, importer := range s.importers(path) {
+		edits, err := s.rewriteObjectKey(importer, target, newPath.IDA())
+		if err != nil {
+			return nil, err
+		}
+		if len(edits) > 0 {
+			edit.Changes[lsp.File(importer)] = edits
+		}
+	}
+	return edit, nil
+}
+
+// mountedFields returns the fields of ir that target was imported as, found by the
+// mentions of target they share.
+func mountedFields(ir *d2ir.Map, target *d2ir.Field) []*d2ir.Field {
+	mentions := map[d2ast.Range]bool{}
+	for _, ref := range target.References {
+		if ref.String != nil {
+			mentions[ref.String.GetRange()] = true
+		}
+	}
+
+	fields := []*d2ir.Field{}
+	walkIR(ir, func(node d2ir.Node) {
+		field, ok := node.(*d2ir.Field)
+		if !ok {
+			return
+		}
+		for _, ref := range field.References {
+			if ref.String != nil && mentions[ref.String.GetRange()] {
+				fields = append(fields, field)
+				return
+			}
+		}
+	})
+	return fields
+}
+
+// rewriteObjectKey replaces the key of target with newKey wherever file mentions it,
+// under each key file imports target's file at. Keys are rewritten relative to the
+// map they are written in, which fails for mentions inside a container the object
+// moves out of.
+func (s *State) rewriteObjectKey(file string, target *d2ir.Field, newKey []d2ast.String) ([]lsp.TextEdit, error) {
+	_, ir, err := s.compileFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile %s: %w", file, err)
+	}
+
+	// The first element is the name of the root field.
+	key := d2ir.IDA(target)[1:]
+	edits := []lsp.TextEdit{}
+	for _, field := range mountedFields(ir, target) {
+		ida := d2ir.IDA(field)[1:]
+		mount := ida[:len(ida)-len(key)]
+		moved := append(append([]d2ast.String{}, mount...), newKey...)
+
+		for _, ref := range field.References {
+			if ref.String == nil || ref.DueToGlob() || ref.String.GetRange().Path != file {
+				continue
+			}
+			path := ref.KeyPath.Path
+			i := 0
+			for i < len(path) && path[i].Unbox() != ref.String {
+				i++
+			}
+			// The length of the key of the map the mention is written in.
+			depth := len(ida) - (i + 1)
+			for j := len(mount); j < depth; j++ {
+				if j >= len(moved) || moved[j].ScalarString() != ida[j].ScalarString() {
+					return nil, fmt.Errorf("%s mentions %s inside a container it moves out of", file, formatIDA(ida))
+				}
+			}
+			edits = append(edits, lsp.TextEdit{
+				Range: lsp.Range{
+					Start: toLspPosition(path[0].Unbox().GetRange().Start),
+					End:   toLspPosition(ref.String.GetRange().End),
+				},
+				NewText: formatIDA(moved[depth:]),
+			})
+		}
+	}
+	return edits, nil
+}
@@ -0,0 +1,118 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestMoveObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		main     string
+		position lsp.Position
+		titles   []string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "into container",
+			main:     "...@shared\napp -> db\ndb.shape: cylinder\n",
+			position: lsp.Position{Line: 1, Character: 0},
+			titles:   []string{"Move db into backend"},
+			want: map[string]string{
+				"shared.d2": "backend: {\n  api\n  db\n}\n\napi -> backend.db\n",
+				"main.d2":   "...@shared\napp -> backend.db\nbackend.db.shape: cylinder\n",
+			},
+		},
+		{
+			name:     "keyed import",
+			main:     "x: @shared\ndb -> x.db\nx: {db.shape: cylinder}\n",
+			position: lsp.Position{Line: 1, Character: 0},
+			titles:   []string{"Move db into backend"},
+			want: map[string]string{
+				"shared.d2": "backend: {\n  api\n  db\n}\n\napi -> backend.db\n",
+				"main.d2":   "x: @shared\ndb -> x.backend.db\nx: {backend.db.shape: cylinder}\n",
+			},
+		},
+		{
+			name:     "out of container",
+			main:     "...@shared\napp -> db\ndb.shape: cylinder\n",
+			position: lsp.Position{Line: 0, Character: 10},
+			titles:   []string{"Move api out of backend"},
+			want: map[string]string{
+				"shared.d2": "backend\ndb\napi -> db\napi 2\n",
+			},
+		},
+		{
+			name:     "mentioned inside container",
+			main:     "...@shared\nbackend: {api.shape: circle}\n",
+			position: lsp.Position{Line: 0, Character: 10},
+			titles:   []string{"Move api out of backend"},
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := writeTestFiles(t, map[string]string{
+				"main.d2": test.main,
+			})
+			text := "backend: {api}\ndb\napi -> db\n"
+			uri := lsp.File(filepath.Join(root, "shared.d2"))
+			state := newTestState(t)
+			state.AddWorkspaceFolders([]lsp.WorkspaceFolder{{URI: lsp.File(root), Name: "test"}})
+			state.OpenDocument(uri, 1, text)
+
+			context := lsp.CodeActionContext{Only: []lsp.CodeActionKind{lsp.RefactorMove}}
+			response := state.CodeActions(1, uri, lsp.Range{Start: test.position, End: test.position}, context)
+			titles := []string{}
+			for _, action := range response.Result {
+				titles = append(titles, action.Title)
+			}
+			if diff := cmp.Diff(test.titles, titles); diff != "" {
+				t.Fatalf("CodeActions() titles mismatch (-want +got):\n%s", diff)
+			}
+
+			command := response.Result[0].Command
+			arguments := []json.RawMessage{}
+			for _, argument := range command.Arguments {
+				raw, err := json.Marshal(argument)
+				if err != nil {
+					t.Fatal(err)
+				}
+				arguments = append(arguments, raw)
+			}
+			result, apply := state.ExecuteCommand(2, command.Command, arguments)
+			if test.wantErr {
+				if result.Error == nil {
+					t.Fatalf("ExecuteCommand() = %+v, want an error", apply)
+				}
+				return
+			}
+			if result.Error != nil {
+				t.Fatalf("ExecuteCommand() error = %+v", result.Error)
+			}
+
+			got := map[string]string{}
+			for changed, edits := range apply.Edit.Changes {
+				before := text
+				if changed != uri {
+					content, err := os.ReadFile(changed.Filename())
+					if err != nil {
+						t.Fatal(err)
+					}
+					before = string(content)
+				}
+				got[filepath.Base(changed.Filename())] = applyEdits(before, edits)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("moved files mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// freshAST parses text again, the tree can be modified freely unlike the one kept
+// for the document. Returns nil when text doesn't parse.
+func freshAST(uri lsp.DocumentURI, text string) *d2ast.Map {
+	ast, err := d2parser.Parse(uri.Filename(), strings.NewReader(text), &d2parser.ParseOptions{
+		UTF16Pos: true,
+	})
+	if err != nil {
+		return nil
+	}
+	return ast
+}
+
+// keyAtPosition returns the innermost key under the cursor in a fresh parse of the
+// document, along with the map holding it.
+func keyAtPosition(uri lsp.DocumentURI, text string, position lsp.Position) (*d2ast.Key, *d2ast.Map) {
+	ast := freshAST(uri, text)
+	if ast == nil {
+		return nil, nil
+	}
+
+	chain := nodesAtPosition(ast, position)
+	for i := len(chain) - 1; i > 0; i-- {
+		if key, ok := chain[i].(*d2ast.Key); ok {
+			parent, _ := chain[i-1].(*d2ast.Map)
+			return key, parent
+		}
+	}
+	return nil, nil
+}
+
+// isPlainKey reports whether key only sets a field, without edges or globs filters.
+func isPlainKey(key *d2ast.Key) bool {
+	return key.Key != nil && len(key.Edges) == 0 && !key.Ampersand && !key.NotAmpersand
+}
+
+// lineMap returns an empty map the formatter prints over several lines, starting at
+// line. Maps starting at the origin are taken for the file map.
+func lineMap(line int) *d2ast.Map {
+	return &d2ast.Map{
+		Range: d2ast.Range{
+			Start: d2ast.Position{Line: line, Column: 1},
+			End:   d2ast.Position{Line: line + 2},
+		},
+	}
+}
+
+// onLine places key on the line after prev, so the formatter doesn't separate them
+// with a blank line.
+func onLine(key *d2ast.Key, prev d2ast.Node) {
+	line := prev.GetRange().End.Line + 1
+	key.Range = d2ast.Range{
+		Start: d2ast.Position{Line: line},
+		End:   d2ast.Position{Line: line},
+	}
+}
+
+// nestKey turns the dotted key into maps nested one segment at a time.
+func nestKey(key *d2ast.Key) *d2ast.Key {
+	line := key.Range.Start.Line
+	path := key.Key.Path
+	inner := *key
+	inner.Key = &d2ast.KeyPath{Path: path[len(path)-1:]}
+	inner.Range = d2ast.Range{
+		Start: d2ast.Position{Line: line + 1},
+		End:   d2ast.Position{Line: line + 1},
+	}
+
+	nested := &inner
+	for i := len(path) - 2; i >= 0; i-- {
+		m := lineMap(line)
+		m.Nodes = []d2ast.MapNodeBox{d2ast.MakeMapNodeBox(nested)}
+		nested = &d2ast.Key{
+			Range: nested.Range,
+			Key:   &d2ast.KeyPath{Path: path[i : i+1]},
+			Value: d2ast.MakeValueBox(m),
+		}
+	}
+	nested.Range = key.Range
+	return nested
+}
+
+// siblingMap returns the key declaring name with a map in m, before the key before
+// or anywhere in m when before is nil. Keys merged into that map must still be set
+// last, so there is none when a later key of m also sets something on name.
+func siblingMap(m *d2ast.Map, name d2ast.String, before *d2ast.Key) *d2ast.Key {
+	var sibling *d2ast.Key
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == before && before != nil {
+			break
+		}
+		if key == nil || !isPlainKey(key) || !sameKeySegment(key.Key.Path[0].Unbox(), name) {
+			continue
+		}
+		sibling = nil
+		if len(key.Key.Path) == 1 && key.Value.Map != nil {
+			sibling = key
+		}
+	}
+	return sibling
+}
+
+// sameKeySegment reports whether a and b name the same field, IDs are case
+// insensitive.
+func sameKeySegment(a, b d2ast.String) bool {
+	return strings.EqualFold(a.ScalarString(), b.ScalarString())
+}
+
+// mergeKey adds the dotted key to m, going into the maps m already has for the
+// leading segments.
+func mergeKey(m *d2ast.Map, key *d2ast.Key) {
+	path := key.Key.Path
+	if len(path) > 1 {
+		if sibling := siblingMap(m, path[0].Unbox(), nil); sibling != nil {
+			rest := *key
+			rest.Key = &d2ast.KeyPath{Path: path[1:]}
+			mergeKey(sibling.Value.Map, &rest)
+			return
+		}
+	}
+
+	var prev d2ast.Node = m
+	if len(m.Nodes) > 0 {
+		prev = m.Nodes[len(m.Nodes)-1].Unbox()
+	}
+	onLine(key, prev)
+	if len(path) > 1 {
+		key = nestKey(key)
+	}
+	m.Nodes = append(m.Nodes, d2ast.MakeMapNodeBox(key))
+}
+
+// collapseKey folds maps holding a single field into the key, as long as nothing
+// else is set on them.
+func collapseKey(key *d2ast.Key) *d2ast.Key {
+	collapsed := *key
+	for collapsed.Primary.Unbox() == nil && collapsed.Value.Map != nil && len(collapsed.Value.Map.Nodes) == 1 {
+		child := collapsed.Value.Map.Nodes[0].MapKey
+		if child == nil || !isPlainKey(child) {
+			break
+		}
+		path := append(append([]*d2ast.StringBox{}, collapsed.Key.Path...), child.Key.Path...)
+		collapsed.Key = &d2ast.KeyPath{Path: path}
+		collapsed.Primary = child.Primary
+		collapsed.Value = child.Value
+	}
+	if len(collapsed.Key.Path) == len(key.Key.Path) {
+		return nil
+	}
+	return &collapsed
+}
+
+// formatAt formats node for the line it starts on.
+func formatAt(text string, node d2ast.Node) string {
+	line := lineAt(text, node.GetRange().Start.Line)
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	return indentLines(d2format.Format(node), indent)
+}
+
+// deleteKey removes key along with its lines when nothing else is on them.
+func deleteKey(text string, key *d2ast.Key) lsp.TextEdit {
+	r := toLspRange(key.Range)
+	before := lineAt(text, r.Start.Line)[:byteIndex(lineAt(text, r.Start.Line), r.Start.Character)]
+	after := lineAt(text, r.End.Line)[byteIndex(lineAt(text, r.End.Line), r.End.Character):]
+	if strings.TrimSpace(before) == "" && strings.TrimSpace(after) == "" {
+		r = lsp.Range{
+			Start: lsp.Position{Line: r.Start.Line},
+			End:   lsp.Position{Line: r.End.Line + 1},
+		}
+	}
+	return lsp.TextEdit{Range: r}
+}
+
+// expandKey nests a dotted key into maps, merging it into a map a sibling already
+// declares for its first segment.
+func expandKey(uri lsp.DocumentURI, text string, key *d2ast.Key, parent *d2ast.Map) *lsp.CodeAction {
+	if !isPlainKey(key) || len(key.Key.Path) < 2 {
+		return nil
+	}
+
+	edits := []lsp.TextEdit{}
+	if sibling := siblingMap(parent, key.Key.Path[0].Unbox(), key); sibling != nil {
+		rest := *key
+		rest.Key = &d2ast.KeyPath{Path: key.Key.Path[1:]}
+		mergeKey(sibling.Value.Map, &rest)
+		edits = append(edits, deleteKey(text, key), lsp.TextEdit{
+			Range:   toLspRange(sibling.Range),
+			NewText: formatAt(text, sibling),
+		})
+		// Edits may not overlap and are easier to follow in document order.
+		if comparePositions(edits[1].Range.Start, edits[0].Range.Start) < 0 {
+			edits[0], edits[1] = edits[1], edits[0]
+		}
+	} else {
+		edits = append(edits, lsp.TextEdit{
+			Range:   toLspRange(key.Range),
+			NewText: formatAt(text, nestKey(key)),
+		})
+	}
+
+	return &lsp.CodeAction{
+		Title: "Expand into nested maps",
+		Kind:  lsp.RefactorRewrite,
+		Edit: &lsp.WorkspaceEdit{
+			Changes: map[lsp.DocumentURI][]lsp.TextEdit{uri: edits},
+		},
+	}
+}
+
+// collapseMap turns maps holding a single field into a dotted key.
+func collapseMap(uri lsp.DocumentURI, text string, key *d2ast.Key) *lsp.CodeAction {
+	if !isPlainKey(key) {
+		return nil
+	}
+	collapsed := collapseKey(key)
+	if collapsed == nil {
+		return nil
+	}
+
+	return &lsp.CodeAction{
+		Title: "Collapse into dotted key",
+		Kind:  lsp.RefactorRewrite,
+		Edit: &lsp.WorkspaceEdit{
+			Changes: map[lsp.DocumentURI][]lsp.TextEdit{
+				uri: {{Range: toLspRange(key.Range), NewText: formatAt(text, collapsed)}},
+			},
+		},
+	}
+}
+
+// rewriteKeyActions returns the actions converting the key under the cursor between
+// its dotted and nested forms.
+func (s *State) rewriteKeyActions(uri lsp.DocumentURI, position lsp.Position) []lsp.CodeAction {
+	text := s.Documents[uri].Text
+	actions := []lsp.CodeAction{}
+	// Each action gets its own parse since merging modifies the tree.
+	if key, parent := keyAtPosition(uri, text, position); key != nil && parent != nil {
+		if action := expandKey(uri, text, key, parent); action != nil {
+			actions = append(actions, *action)
+		}
+	}
+	if key, _ := keyAtPosition(uri, text, position); key != nil {
+		if action := collapseMap(uri, text, key); action != nil {
+			actions = append(actions, *action)
+		}
+	}
+	return actions
+}
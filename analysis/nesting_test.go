@@ -0,0 +1,112 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestRewriteKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		position lsp.Position
+		want     map[string]string
+	}{
+		{
+			name:     "expand",
+			text:     "a.style.fill: red\nb\n",
+			position: lsp.Position{Line: 0, Character: 0},
+			want: map[string]string{
+				"Expand into nested maps": "a: {\n  style: {\n    fill: red\n  }\n}\nb\n",
+			},
+		},
+		{
+			name:     "expand nested",
+			text:     "x: {\n  a.b: {c}\n}\n",
+			position: lsp.Position{Line: 1, Character: 2},
+			want: map[string]string{
+				"Expand into nested maps":  "x: {\n  a: {\n    b: {c}\n  }\n}\n",
+				"Collapse into dotted key": "x: {\n  a.b.c\n}\n",
+			},
+		},
+		{
+			name:     "expand into sibling map",
+			text:     "A: {\n  style: {\n    stroke: blue\n  }\n}\n\nb\na.style.fill: red\n",
+			position: lsp.Position{Line: 7, Character: 1},
+			want: map[string]string{
+				"Expand into nested maps": "A: {\n  style: {\n    stroke: blue\n    fill: red\n  }\n}\n\nb\n",
+			},
+		},
+		{
+			name:     "expand into earlier sibling map with new field",
+			text:     "a: {\n  shape: circle\n}\na.style.fill: red\n",
+			position: lsp.Position{Line: 3, Character: 1},
+			want: map[string]string{
+				"Expand into nested maps": "a: {\n  shape: circle\n  style: {\n    fill: red\n  }\n}\n",
+			},
+		},
+		{
+			name:     "expand before later sibling map",
+			text:     "a.style.fill: red\na: {style.fill: blue}\n",
+			position: lsp.Position{Line: 0, Character: 1},
+			want: map[string]string{
+				"Expand into nested maps": "a: {\n  style: {\n    fill: red\n  }\n}\na: {style.fill: blue}\n",
+			},
+		},
+		{
+			name:     "expand past sibling map set again later",
+			text:     "a: {style: {stroke: blue}}\na.style.fill: green\na.style.fill: red\n",
+			position: lsp.Position{Line: 2, Character: 1},
+			want: map[string]string{
+				"Expand into nested maps": "a: {style: {stroke: blue}}\na.style.fill: green\na: {\n  style: {\n    fill: red\n  }\n}\n",
+			},
+		},
+		{
+			name:     "collapse",
+			text:     "a: {\n  style: {\n    fill: red\n  }\n}\n",
+			position: lsp.Position{Line: 0, Character: 0},
+			want: map[string]string{
+				"Collapse into dotted key": "a.style.fill: red\n",
+			},
+		},
+		{
+			name:     "collapse stops at several fields",
+			text:     "a: {\n  style: {\n    fill: red\n    stroke: blue\n  }\n}\n",
+			position: lsp.Position{Line: 0, Character: 0},
+			want: map[string]string{
+				"Collapse into dotted key": "a.style: {\n  fill: red\n  stroke: blue\n}\n",
+			},
+		},
+		{
+			name:     "label",
+			text:     "a: A {\n  b\n}\n",
+			position: lsp.Position{Line: 0, Character: 0},
+			want:     map[string]string{},
+		},
+		{
+			name:     "edge",
+			text:     "a.b -> c\n",
+			position: lsp.Position{Line: 0, Character: 0},
+			want:     map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			uri := openTestDocument(t, state, "test.d2", test.text)
+
+			context := lsp.CodeActionContext{Only: []lsp.CodeActionKind{lsp.RefactorRewrite}}
+			response := state.CodeActions(1, uri, lsp.Range{Start: test.position, End: test.position}, context)
+			got := map[string]string{}
+			for _, action := range response.Result {
+				got[action.Title] = applyEdits(test.text, action.Edit.Changes[uri])
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("CodeActions() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
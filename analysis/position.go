@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+)
+
+func toLspPosition(position d2ast.Position) lsp.Position {
+	return lsp.Position{
+		Line:      position.Line,
+		Character: position.Column,
+	}
+}
+
+func toLspRange(r d2ast.Range) lsp.Range {
+	return lsp.Range{
+		Start: toLspPosition(r.Start),
+		End:   toLspPosition(r.End),
+	}
+}
+
+func toLspLocation(r d2ast.Range) lsp.Location {
+	return lsp.Location{
+		URI:   lsp.File(r.Path),
+		Range: toLspRange(r),
+	}
+}
+
+// comparePositions returns -1, 0 or 1 when a is before, equal to or after b.
+func comparePositions(a, b lsp.Position) int {
+	switch {
+	case a.Line < b.Line:
+		return -1
+	case a.Line > b.Line:
+		return 1
+	case a.Character < b.Character:
+		return -1
+	case a.Character > b.Character:
+		return 1
+	}
+	return 0
+}
+
+// rangeContains reports whether position is within r.
+// The end is inclusive so that a cursor placed right after an identifier still
+// refers to it.
+func rangeContains(r d2ast.Range, position lsp.Position) bool {
+	lspRange := toLspRange(r)
+	return comparePositions(lspRange.Start, position) <= 0 && comparePositions(position, lspRange.End) <= 0
+}
+
+// nodesAtPosition returns the chain of nodes enclosing position, from the outermost
+// node to the innermost one.
+func nodesAtPosition(root d2ast.Node, position lsp.Position) []d2ast.Node {
+	// Documents that aren't open have a nil map, which isn't a nil node.
+	if m, ok := root.(*d2ast.Map); root == nil || ok && m == nil {
+		return nil
+	}
+
+	switch root.(type) {
+	case *d2ast.Map, *d2ast.Array, *d2ast.Key:
+		if !rangeContains(root.GetRange(), position) {
+			return nil
+		}
+	}
+
+	// The parser doesn't always extend the range of a string over the substitutions
+	// it contains, so children are searched even when the parent doesn't match.
+	for _, child := range root.Children() {
+		if chain := nodesAtPosition(child, position); chain != nil {
+			return append([]d2ast.Node{root}, chain...)
+		}
+	}
+	if rangeContains(root.GetRange(), position) {
+		return []d2ast.Node{root}
+	}
+
+	return nil
+}
+
+// lineAt returns line i of text, or an empty string past the end.
+func lineAt(text string, i int) string {
+	lines := strings.Split(text, "\n")
+	if i < 0 || i >= len(lines) {
+		return ""
+	}
+	return lines[i]
+}
+
+// byteIndex converts a UTF-16 column on line into a byte index into line.
+func byteIndex(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// utf16Len returns the length of s in UTF-16 code units, as LSP columns count them.
+func utf16Len(s string) int {
+	units := 0
+	for _, r := range s {
+		units += utf16.RuneLen(r)
+	}
+	return units
+}
+
+// textOffset converts position into a byte offset into text.
+func textOffset(text string, position lsp.Position) int {
+	offset := 0
+	for i, line := range strings.SplitAfter(text, "\n") {
+		if i == position.Line {
+			return offset + byteIndex(line, position.Character)
+		}
+		offset += len(line)
+	}
+	return len(text)
+}
@@ -0,0 +1,273 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2ir"
+)
+
+// objectAtPosition returns the shape or connection under the cursor. Attributes
+// resolve to the object they belong to.
+func objectAtPosition(ast *d2ast.Map, ir *d2ir.Map, position lsp.Position) d2ir.Node {
+	keys := nodesByKey(ir)
+	chain := nodesAtPosition(ast, position)
+	for i := len(chain) - 1; i >= 0; i-- {
+		switch node := chain[i].(type) {
+		case d2ast.String:
+			if field := fieldAtString(ir, node); field != nil {
+				return ownerObject(field)
+			}
+		case *d2ast.Key:
+			if owner, ok := keys[node]; ok {
+				return ownerObject(owner)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ownerObject walks up from reserved fields to the shape or connection they configure.
+func ownerObject(node d2ir.Node) d2ir.Node {
+	for {
+		field, ok := node.(*d2ir.Field)
+		if !ok {
+			return node
+		}
+		if field.Root() {
+			return nil
+		}
+		if !isReservedField(field) {
+			return field
+		}
+		node = d2ir.ParentMap(field).Parent()
+	}
+}
+
+func (s *State) styleProvenance(uri lsp.DocumentURI, object d2ir.Node) *lsp.StyleProvenanceResult {
+	path := uri.Filename()
+	attributes := map[string]*lsp.AttributeProvenance{}
+	addSource := func(name string, source lsp.ProvenanceSource) {
+		attribute, ok := attributes[name]
+		if !ok {
+			attribute = &lsp.AttributeProvenance{
+				Attribute: name,
+				Value:     source.Value,
+			}
+			attributes[name] = attribute
+		}
+		attribute.Sources = append(attribute.Sources, source)
+	}
+
+	// Fields set on the object itself take precedence over its classes, the most
+	// recent assignment winning. Only the winning assignment has its compiled value,
+	// the ones it overrides show what they were written as.
+	walkAttributes(object.Map(), nil, func(name []string, field *d2ir.Field) {
+		seen := map[*d2ast.Key]struct{}{}
+		value := fieldValue(field)
+		for i := len(field.References) - 1; i >= 0; i-- {
+			ref := field.References[i]
+			if !ref.Primary() {
+				continue
+			}
+			if _, ok := seen[ref.Context_.Key]; ok {
+				continue
+			}
+			seen[ref.Context_.Key] = struct{}{}
+
+			r := ref.AST().GetRange()
+			kind := lsp.ProvenanceObject
+			switch {
+			case ref.DueToGlob():
+				kind = lsp.ProvenanceGlob
+			case r.Path != path:
+				kind = lsp.ProvenanceImport
+			case !isHomeRef(field, ref.Context_):
+				kind = lsp.ProvenanceBoard
+			}
+			if len(seen) > 1 {
+				value = keyValue(ref.Context_.Key)
+			}
+			addSource(strings.Join(name, "."), lsp.ProvenanceSource{
+				Kind:     kind,
+				Value:    value,
+				Location: toLspLocation(r),
+			})
+		}
+	})
+
+	// Later classes override earlier ones.
+	classNames := objectClasses(object)
+	for i := len(classNames) - 1; i >= 0; i-- {
+		class := object.Map().GetClassMap(classNames[i])
+		walkAttributes(class, nil, func(name []string, field *d2ir.Field) {
+			addSource(strings.Join(name, "."), lsp.ProvenanceSource{
+				Kind:     lsp.ProvenanceClass,
+				Name:     classNames[i],
+				Value:    fieldValue(field),
+				Location: toLspLocation(variableDefinition(field)),
+			})
+		})
+	}
+
+	result := &lsp.StyleProvenanceResult{
+		Object:     formatIDA(d2ir.BoardIDA(object)),
+		Attributes: []lsp.AttributeProvenance{},
+	}
+	for _, attribute := range attributes {
+		result.Attributes = append(result.Attributes, *attribute)
+	}
+	slices.SortFunc(result.Attributes, func(a, b lsp.AttributeProvenance) int {
+		return strings.Compare(a.Attribute, b.Attribute)
+	})
+
+	return result
+}
+
+// walkAttributes calls fn for every reserved field with a value below m.
+func walkAttributes(m *d2ir.Map, prefix []string, fn func([]string, *d2ir.Field)) {
+	if m == nil {
+		return
+	}
+	for _, field := range m.Fields {
+		if !isReservedField(field) {
+			continue
+		}
+		name := append(slices.Clone(prefix), field.Name.ScalarString())
+		switch field.Name.ScalarString() {
+		case "vars", "classes", "layers", "scenarios", "steps":
+			continue
+		}
+		// Labels and icons can hold a value and a map of their own attributes.
+		if _, ok := field.Composite.(*d2ir.Map); !ok || field.Primary() != nil {
+			fn(name, field)
+		}
+		walkAttributes(field.Map(), name, fn)
+	}
+}
+
+func objectClasses(object d2ir.Node) []string {
+	if object.Map() == nil {
+		return nil
+	}
+	class := object.Map().GetField(d2ast.FlatUnquotedString("class"))
+	if class == nil {
+		return nil
+	}
+	if class.Primary() != nil {
+		return []string{class.Primary().Value.ScalarString()}
+	}
+
+	var names []string
+	if array, ok := class.Composite.(*d2ir.Array); ok {
+		for _, value := range array.Values {
+			if scalar, ok := value.(*d2ir.Scalar); ok {
+				names = append(names, scalar.Value.ScalarString())
+			}
+		}
+	}
+	return names
+}
+
+// fieldValue returns the compiled value of field, with substitutions resolved.
+func fieldValue(field *d2ir.Field) string {
+	if array, ok := field.Composite.(*d2ir.Array); ok && field.Primary() == nil {
+		return formatArray(array.AST().(*d2ast.Array))
+	}
+	return variableValue(field)
+}
+
+// keyValue returns the value written on key, substitutions included.
+func keyValue(key *d2ast.Key) string {
+	if value := key.Value.ScalarBox().Unbox(); value != nil {
+		if text, ok := literalString(value); ok {
+			return text
+		}
+		return d2format.Format(value)
+	}
+	if key.Value.Array != nil {
+		return formatArray(key.Value.Array)
+	}
+	return ""
+}
+
+// formatArray formats array on a single line, d2format spreads it over several.
+func formatArray(array *d2ast.Array) string {
+	values := make([]string, len(array.Nodes))
+	for i, node := range array.Nodes {
+		values[i] = d2format.Format(node.Unbox())
+	}
+	return "[" + strings.Join(values, "; ") + "]"
+}
+
+func (s *State) StyleProvenance(id any, uri lsp.DocumentURI, position lsp.Position) lsp.StyleProvenanceResponse {
+	response := lsp.StyleProvenanceResponse{
+		Response: lsp.NewResponse(id),
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+		return response
+	}
+	if object := objectAtPosition(ast, ir, position); object != nil {
+		response.Result = s.styleProvenance(uri, object)
+	}
+
+	return response
+}
+
+func (s *State) hoverStyleProvenance(uri lsp.DocumentURI, ast *d2ast.Map, ir *d2ir.Map, position lsp.Position) string {
+	object := objectAtPosition(ast, ir, position)
+	if object == nil {
+		return ""
+	}
+	result := s.styleProvenance(uri, object)
+	if len(result.Attributes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Attributes of `%s`**\n", result.Object)
+	for _, attribute := range result.Attributes {
+		fmt.Fprintf(&b, "\n- `%s`", attribute.Attribute)
+		if attribute.Value != "" {
+			fmt.Fprintf(&b, ": `%s`", attribute.Value)
+		}
+		for i, source := range attribute.Sources {
+			if i == 0 {
+				b.WriteString(" from ")
+			} else if i == 1 {
+				b.WriteString(", overrides ")
+			} else {
+				b.WriteString(", ")
+			}
+			b.WriteString(formatProvenanceSource(source))
+		}
+	}
+
+	return b.String()
+}
+
+func formatProvenanceSource(source lsp.ProvenanceSource) string {
+	var b strings.Builder
+	b.WriteString(string(source.Kind))
+	if source.Name != "" {
+		fmt.Fprintf(&b, " `%s`", source.Name)
+	}
+	fmt.Fprintf(
+		&b,
+		" ([%s:%d](%s#L%d))",
+		filepath.Base(source.Location.URI.Filename()),
+		source.Location.Range.Start.Line+1,
+		source.Location.URI,
+		source.Location.Range.Start.Line+1,
+	)
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestStyleProvenance(t *testing.T) {
+	state := newTestState(t)
+	text := "classes: {\n  k: {style.fill: blue; style.stroke: black}\n  j: {style.fill: green}\n}\n*.style.opacity: 0.5\na.class: [k; j]\na.style.fill: red\n"
+	uri := openTestDocument(t, state, "test.d2", text)
+
+	response := state.StyleProvenance(1, uri, lsp.Position{Line: 6, Character: 0})
+	if response.Result == nil {
+		t.Fatal("StyleProvenance() returned no result")
+	}
+
+	type source struct {
+		Kind  lsp.ProvenanceKind
+		Name  string
+		Value string
+		Line  int
+	}
+	got := map[string][]source{}
+	for _, attribute := range response.Result.Attributes {
+		for _, s := range attribute.Sources {
+			got[attribute.Attribute] = append(got[attribute.Attribute], source{s.Kind, s.Name, s.Value, s.Location.Range.Start.Line})
+		}
+	}
+	want := map[string][]source{
+		"class": {{lsp.ProvenanceObject, "", "[k; j]", 5}},
+		"style.fill": {
+			{lsp.ProvenanceObject, "", "red", 6},
+			{lsp.ProvenanceClass, "j", "green", 2},
+			{lsp.ProvenanceClass, "k", "blue", 1},
+		},
+		"style.opacity": {{lsp.ProvenanceGlob, "", "0.5", 4}},
+		"style.stroke":  {{lsp.ProvenanceClass, "k", "black", 1}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("StyleProvenance() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStyleProvenanceSubstitutions(t *testing.T) {
+	state := newTestState(t)
+	text := "vars: {\n  c: red\n  d2-config: {theme-overrides: {B1: \"#000\"}}\n}\na.style.fill: blue\na.style.fill: ${c}\n"
+	uri := openTestDocument(t, state, "test.d2", text)
+
+	response := state.StyleProvenance(1, uri, lsp.Position{Line: 5, Character: 0})
+	if response.Result == nil {
+		t.Fatal("StyleProvenance() returned no result")
+	}
+
+	type source struct {
+		Kind  lsp.ProvenanceKind
+		Value string
+		Line  int
+	}
+	got := map[string][]source{}
+	for _, attribute := range response.Result.Attributes {
+		for _, s := range attribute.Sources {
+			name := attribute.Attribute + ": " + attribute.Value
+			got[name] = append(got[name], source{s.Kind, s.Value, s.Location.Range.Start.Line})
+		}
+	}
+	// The theme overrides don't say which attributes they affect, so they aren't listed.
+	want := map[string][]source{
+		"style.fill: red": {
+			{lsp.ProvenanceObject, "red", 5},
+			{lsp.ProvenanceObject, "blue", 4},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("StyleProvenance() mismatch (-want +got):\n%s", diff)
+	}
+}
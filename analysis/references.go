@@ -0,0 +1,294 @@
+package analysis
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2ir"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// referenceTarget returns the shape or container whose ID is under the cursor,
+// either as part of a key or as the value of a near attribute.
+func referenceTarget(ast *d2ast.Map, ir *d2ir.Map, position lsp.Position) *d2ir.Field {
+	chain := nodesAtPosition(ast, position)
+	for i := len(chain) - 1; i >= 0; i-- {
+		str, ok := chain[i].(d2ast.String)
+		if !ok {
+			continue
+		}
+		if field := fieldAtString(ir, str); field != nil {
+			if isReservedField(field) {
+				return nil
+			}
+			return field
+		}
+		if i > 0 {
+			if key, ok := chain[i-1].(*d2ast.Key); ok && key.Value.ScalarBox().Unbox() == str {
+				if near, ok := nodesByKey(ir)[key].(*d2ir.Field); ok {
+					return nearTarget(near)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// nearTarget returns the object a near attribute points to, nil for constants like
+// top-center.
+func nearTarget(near *d2ir.Field) *d2ir.Field {
+	if near.Name.ScalarString() != "near" || !isReservedField(near) || near.Primary() == nil {
+		return nil
+	}
+	keyPath, err := d2parser.ParseKey(near.Primary().Value.ScalarString())
+	if err != nil {
+		return nil
+	}
+	// Near keys are absolute within their board.
+	return boardRoot(near).GetField(keyPath.IDA()...)
+}
+
+// sharesReference reports whether field was compiled from any of the key path
+// segments in ranges. Imports and scenarios produce distinct fields for the same
+// object, but they keep pointing at the source it was written in.
+func sharesReference(field *d2ir.Field, ranges map[d2ast.Range]struct{}) bool {
+	for _, ref := range field.References {
+		if ref.String == nil || ref.DueToGlob() {
+			continue
+		}
+		if _, ok := ranges[ref.String.GetRange()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatches returns the ranges of glob patterns that matched field.
+// Matching an existing field doesn't add a reference to it, only to the fields the
+// glob then sets below it.
+func globMatches(field *d2ir.Field) []d2ast.Range {
+	if field.Map() == nil {
+		return nil
+	}
+
+	var ranges []d2ast.Range
+	for _, child := range field.Map().Fields {
+		for _, ref := range child.References {
+			if !ref.DueToGlob() || ref.KeyPath == nil {
+				continue
+			}
+			for i := 1; i < len(ref.KeyPath.Path); i++ {
+				if ref.KeyPath.Path[i].Unbox() != ref.String {
+					continue
+				}
+				if pattern, ok := ref.KeyPath.Path[i-1].Unbox().(*d2ast.UnquotedString); ok && pattern.Pattern != nil {
+					ranges = append(ranges, pattern.GetRange())
+				}
+			}
+		}
+	}
+	return ranges
+}
+
+// occurrence is a place in the source where an object is mentioned.
+type occurrence struct {
+	Range d2ast.Range
+	// Field is the object as compiled where it is mentioned.
+	Field *d2ir.Field
+	// Edge endpoints and near values refer to the object without declaring it or
+	// setting any of its attributes.
+	Read bool
+	// Glob patterns match the object without naming it.
+	Glob bool
+}
+
+// targetReferences returns the key path segments target was compiled from.
+func targetReferences(target *d2ir.Field) map[d2ast.Range]struct{} {
+	ranges := map[d2ast.Range]struct{}{}
+	for _, ref := range target.References {
+		if ref.String != nil && !ref.DueToGlob() {
+			ranges[ref.String.GetRange()] = struct{}{}
+		}
+	}
+	return ranges
+}
+
+// occurrences returns every mention in ir of the object compiled from ranges.
+func occurrences(ir *d2ir.Map, ranges map[d2ast.Range]struct{}, includeGlobs bool) []occurrence {
+	var found []occurrence
+	matches := map[*d2ir.Field]struct{}{}
+	walkIR(ir, func(node d2ir.Node) {
+		field, ok := node.(*d2ir.Field)
+		if !ok || !sharesReference(field, ranges) {
+			return
+		}
+		matches[field] = struct{}{}
+		for _, ref := range field.References {
+			if ref.String != nil && !ref.DueToGlob() {
+				found = append(found, occurrence{
+					Range: ref.String.GetRange(),
+					Field: field,
+					Read:  ref.Context_.Edge != nil,
+				})
+			}
+		}
+		if includeGlobs {
+			for _, r := range globMatches(field) {
+				found = append(found, occurrence{Range: r, Field: field, Glob: true})
+			}
+		}
+	})
+	walkIR(ir, func(node d2ir.Node) {
+		field, ok := node.(*d2ir.Field)
+		if !ok {
+			return
+		}
+		target := nearTarget(field)
+		if _, ok := matches[target]; !ok {
+			return
+		}
+		value, ok := field.LastPrimaryRef().Context().Key.Value.ScalarBox().Unbox().(d2ast.String)
+		if !ok {
+			return
+		}
+		if r, ok := keySegment(value, len(d2ir.BoardIDA(target))-1); ok {
+			found = append(found, occurrence{Range: r, Field: target, Read: true})
+		}
+	})
+
+	return found
+}
+
+// keySegment returns the range of the segment at index in value, a string holding a
+// key path such as the value of near.
+func keySegment(value d2ast.String, index int) (d2ast.Range, bool) {
+	keyPath, err := d2parser.ParseKey(value.ScalarString())
+	if err != nil || index >= len(keyPath.Path) {
+		return d2ast.Range{}, false
+	}
+
+	r := value.GetRange()
+	offset := 0
+	switch value.(type) {
+	case *d2ast.DoubleQuotedString, *d2ast.SingleQuotedString:
+		offset = 1
+	}
+	segment := keyPath.Path[index].Unbox().GetRange()
+	r.Start.Column += offset + segment.Start.Column
+	r.Start.Byte += offset + segment.Start.Byte
+	r.End = r.Start
+	r.End.Column += segment.End.Column - segment.Start.Column
+	r.End.Byte += segment.End.Byte - segment.Start.Byte
+	return r, true
+}
+
+// workspaceOccurrences returns the mentions of target, compiled from the document at
+// uri, in that document and in every file importing it.
+func (s *State) workspaceOccurrences(uri lsp.DocumentURI, ir *d2ir.Map, target *d2ir.Field, includeGlobs bool) []occurrence {
+	ranges := targetReferences(target)
+	var found []occurrence
+	seen := map[d2ast.Range]struct{}{}
+	collect := func(ir *d2ir.Map) {
+		for _, o := range occurrences(ir, ranges, includeGlobs) {
+			if _, ok := seen[o.Range]; ok {
+				continue
+			}
+			seen[o.Range] = struct{}{}
+			found = append(found, o)
+		}
+	}
+
+	collect(ir)
+	// Files importing this one, directly or not, may refer to the same objects.
+	for _, importer := range s.importers(uri.Filename()) {
+		_, ir, err := s.compileFile(importer)
+		if err != nil {
+			s.logger.Printf("could not compile %s: %v", importer, err)
+			continue
+		}
+		collect(ir)
+	}
+
+	return found
+}
+
+func (s *State) References(id any, uri lsp.DocumentURI, position lsp.Position, includeDeclaration bool) lsp.ReferencesResponse {
+	response := lsp.ReferencesResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.Location{},
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+		return response
+	}
+	target := referenceTarget(ast, ir, position)
+	if target == nil {
+		return response
+	}
+	declaration := fieldDeclaration(target)
+	for _, o := range s.workspaceOccurrences(uri, ir, target, s.options.References.IncludeGlobs) {
+		location := toLspLocation(o.Range)
+		if !includeDeclaration && location == declaration {
+			continue
+		}
+		response.Result = append(response.Result, location)
+	}
+
+	slices.SortFunc(response.Result, func(a, b lsp.Location) int {
+		if c := strings.Compare(string(a.URI), string(b.URI)); c != 0 {
+			return c
+		}
+		return comparePositions(a.Range.Start, b.Range.Start)
+	})
+
+	return response
+}
+
+func (s *State) DocumentHighlight(id any, uri lsp.DocumentURI, position lsp.Position) lsp.DocumentHighlightResponse {
+	response := lsp.DocumentHighlightResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.DocumentHighlight{},
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+		return response
+	}
+	target := referenceTarget(ast, ir, position)
+	if target == nil {
+		return response
+	}
+
+	// A mention can be both, e.g. an edge endpoint that also declares the object.
+	kinds := map[d2ast.Range]lsp.DocumentHighlightKind{}
+	for _, o := range occurrences(ir, targetReferences(target), false) {
+		if o.Range.Path != uri.Filename() {
+			continue
+		}
+		kind := lsp.DocumentHighlightKindWrite
+		if o.Read {
+			kind = lsp.DocumentHighlightKindRead
+		}
+		if existing, ok := kinds[o.Range]; ok && existing == lsp.DocumentHighlightKindWrite {
+			continue
+		}
+		kinds[o.Range] = kind
+	}
+	for r, kind := range kinds {
+		response.Result = append(response.Result, lsp.DocumentHighlight{
+			Range: toLspRange(r),
+			Kind:  kind,
+		})
+	}
+	slices.SortFunc(response.Result, func(a, b lsp.DocumentHighlight) int {
+		return comparePositions(a.Range.Start, b.Range.Start)
+	})
+
+	return response
+}
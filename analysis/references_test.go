@@ -0,0 +1,101 @@
+package analysis_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestReferences(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"shared.d2": "db\n*.style.fill: red\n",
+		"test.d2":   "...@shared\napp -> db\nlabel: {near: db}\n",
+		"other.d2":  "...@test\ndb.shape: cylinder\n",
+		"unused.d2": "db\n",
+	})
+
+	tests := []struct {
+		name               string
+		includeDeclaration bool
+		includeGlobs       bool
+		want               []string
+	}{
+		{
+			name: "without declaration",
+			want: []string{
+				"other.d2:2:1",
+				"test.d2:2:8",
+				"test.d2:3:15",
+			},
+		},
+		{
+			name:               "with declaration",
+			includeDeclaration: true,
+			want: []string{
+				"other.d2:2:1",
+				"shared.d2:1:1",
+				"test.d2:2:8",
+				"test.d2:3:15",
+			},
+		},
+		{
+			name:         "with globs",
+			includeGlobs: true,
+			want: []string{
+				"other.d2:2:1",
+				"shared.d2:2:1",
+				"test.d2:2:8",
+				"test.d2:3:15",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			state.SetInitializationOptions(lsp.InitializationOptions{
+				References: lsp.ReferencesOptions{IncludeGlobs: test.includeGlobs},
+			})
+			state.AddWorkspaceFolders([]lsp.WorkspaceFolder{{URI: lsp.File(root), Name: "test"}})
+			uri := lsp.File(filepath.Join(root, "test.d2"))
+			state.OpenDocument(uri, 1, "...@shared\napp -> db\nlabel: {near: db}\n")
+
+			response := state.References(1, uri, lsp.Position{Line: 1, Character: 8}, test.includeDeclaration)
+			got := []string{}
+			for _, location := range response.Result {
+				got = append(got, fmt.Sprintf(
+					"%s:%d:%d",
+					filepath.Base(location.URI.Filename()),
+					location.Range.Start.Line+1,
+					location.Range.Start.Character+1,
+				))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("References() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocumentHighlight(t *testing.T) {
+	state := newTestState(t)
+	uri := openTestDocument(t, state, "test.d2", "db\napp -> db\ndb.shape: cylinder\nlabel: {near: db}\nx: {db}\n")
+
+	response := state.DocumentHighlight(1, uri, lsp.Position{Line: 0, Character: 1})
+	got := []string{}
+	for _, highlight := range response.Result {
+		got = append(got, fmt.Sprintf("%d:%d %d", highlight.Range.Start.Line+1, highlight.Range.Start.Character+1, highlight.Kind))
+	}
+	want := []string{
+		fmt.Sprintf("1:1 %d", lsp.DocumentHighlightKindWrite),
+		fmt.Sprintf("2:8 %d", lsp.DocumentHighlightKindRead),
+		fmt.Sprintf("3:1 %d", lsp.DocumentHighlightKindWrite),
+		fmt.Sprintf("4:15 %d", lsp.DocumentHighlightKindRead),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DocumentHighlight() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2ir"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// renameTarget returns the object under the cursor with the mention the cursor is on.
+func renameTarget(uri lsp.DocumentURI, ast *d2ast.Map, ir *d2ir.Map, position lsp.Position) (*d2ir.Field, d2ast.Range, bool) {
+	target := referenceTarget(ast, ir, position)
+	if target == nil {
+		return nil, d2ast.Range{}, false
+	}
+	for _, o := range occurrences(ir, targetReferences(target), false) {
+		if o.Range.Path == uri.Filename() && rangeContains(o.Range, position) {
+			return target, o.Range, true
+		}
+	}
+	return nil, d2ast.Range{}, false
+}
+
+// validateName returns name formatted as a key, or the reason it can't be used as one.
+func validateName(name string) (string, error) {
+	keyPath, err := d2parser.ParseKey(strings.TrimSpace(name))
+	if err != nil || len(keyPath.Path) != 1 {
+		return "", fmt.Errorf("%q is not a valid ID", name)
+	}
+	str := keyPath.Path[0].Unbox()
+	if _, ok := d2ast.ReservedKeywords[str.ScalarString()]; ok && str.IsUnquoted() {
+		return "", fmt.Errorf("%q is a reserved keyword", name)
+	}
+	return d2format.Format(str), nil
+}
+
+func (s *State) PrepareRename(id any, uri lsp.DocumentURI, position lsp.Position) lsp.PrepareRenameResponse {
+	response := lsp.PrepareRenameResponse{
+		Response: lsp.NewResponse(id),
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+		return response
+	}
+	if target, r, ok := renameTarget(uri, ast, ir, position); ok {
+		response.Result = &lsp.PrepareRenameResult{
+			Range:       toLspRange(r),
+			Placeholder: target.Name.ScalarString(),
+		}
+	}
+
+	return response
+}
+
+func (s *State) Rename(id any, uri lsp.DocumentURI, position lsp.Position, newName string) lsp.RenameResponse {
+	response := lsp.RenameResponse{
+		Response: lsp.NewResponse(id),
+	}
+	fail := func(code lsp.ErrorCode, format string, args ...any) lsp.RenameResponse {
+		response.Error = &lsp.ResponseError{
+			Code:    code,
+			Message: fmt.Sprintf(format, args...),
+		}
+		return response
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		return fail(lsp.RequestFailed, "could not compile %s: %v", uri, err)
+	}
+	target, _, ok := renameTarget(uri, ast, ir, position)
+	if !ok {
+		return fail(lsp.RequestFailed, "there is no shape to rename at the cursor")
+	}
+	text, err := validateName(newName)
+	if err != nil {
+		return fail(lsp.InvalidParams, "cannot rename %q: %v", target.Name.ScalarString(), err)
+	}
+	name, _ := d2parser.ParseKey(text)
+
+	edit := &lsp.WorkspaceEdit{
+		Changes: map[lsp.DocumentURI][]lsp.TextEdit{},
+	}
+	for _, o := range s.workspaceOccurrences(uri, ir, target, false) {
+		// IDs are case insensitive, renaming to a different case is fine.
+		if existing := d2ir.ParentMap(o.Field).GetField(name.Path[0].Unbox()); existing != nil && existing != o.Field {
+			container := "the board"
+			if parent := parentObject(o.Field); parent != nil && d2ir.NodeBoardKind(parent) == "" {
+				container = fmt.Sprintf("%q", objectID(parent))
+			}
+			return fail(
+				lsp.RequestFailed,
+				"cannot rename %q to %s: %q already exists in %s",
+				target.Name.ScalarString(),
+				text,
+				existing.Name.ScalarString(),
+				container,
+			)
+		}
+
+		file := lsp.File(o.Range.Path)
+		edit.Changes[file] = append(edit.Changes[file], lsp.TextEdit{
+			Range:   toLspRange(o.Range),
+			NewText: text,
+		})
+	}
+	for _, edits := range edit.Changes {
+		slices.SortFunc(edits, func(a, b lsp.TextEdit) int {
+			return comparePositions(a.Range.Start, b.Range.Start)
+		})
+	}
+	response.Result = edit
+
+	return response
+}
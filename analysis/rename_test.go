@@ -0,0 +1,113 @@
+package analysis_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestRename(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"shared.d2": "api\n",
+		"other.d2":  "...@test\napi.shape: circle\n",
+	})
+	text := "...@shared\nuser -> api\nlabel: {near: api}\nx: {api}\ngateway\n"
+	uri := lsp.File(filepath.Join(root, "test.d2"))
+
+	tests := []struct {
+		name    string
+		newName string
+		want    []string
+		err     string
+	}{
+		{
+			name:    "across files",
+			newName: "svc",
+			want: []string{
+				"other.d2:2:1 svc",
+				"shared.d2:1:1 svc",
+				"test.d2:2:9 svc",
+				"test.d2:3:15 svc",
+			},
+		},
+		{
+			name:    "spaces",
+			newName: "my api",
+			want: []string{
+				"other.d2:2:1 my api",
+				"shared.d2:1:1 my api",
+				"test.d2:2:9 my api",
+				"test.d2:3:15 my api",
+			},
+		},
+		{
+			name:    "collision",
+			newName: "Gateway",
+			err:     `cannot rename "api" to Gateway: "gateway" already exists in the board`,
+		},
+		{
+			name:    "path",
+			newName: "a.b",
+			err:     `cannot rename "api": "a.b" is not a valid ID`,
+		},
+		{
+			name:    "keyword",
+			newName: "shape",
+			err:     `cannot rename "api": "shape" is a reserved keyword`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			state.AddWorkspaceFolders([]lsp.WorkspaceFolder{{URI: lsp.File(root), Name: "test"}})
+			state.OpenDocument(uri, 1, text)
+
+			prepare := state.PrepareRename(1, uri, lsp.Position{Line: 1, Character: 10})
+			if prepare.Result == nil || prepare.Result.Placeholder != "api" {
+				t.Fatalf("PrepareRename() = %+v, want api", prepare.Result)
+			}
+
+			response := state.Rename(2, uri, lsp.Position{Line: 1, Character: 10}, test.newName)
+			if test.err != "" {
+				if response.Error == nil || response.Error.Message != test.err {
+					t.Errorf("Rename() error = %+v, want %q", response.Error, test.err)
+				}
+				return
+			}
+			if response.Error != nil {
+				t.Fatalf("Rename() error = %+v", response.Error)
+			}
+
+			got := []string{}
+			for uri, edits := range response.Result.Changes {
+				for _, edit := range edits {
+					got = append(got, fmt.Sprintf(
+						"%s:%d:%d %s",
+						filepath.Base(uri.Filename()),
+						edit.Range.Start.Line+1,
+						edit.Range.Start.Character+1,
+						edit.NewText,
+					))
+				}
+			}
+			slices.Sort(got)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Rename() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrepareRenameKeyword(t *testing.T) {
+	state := newTestState(t)
+	uri := openTestDocument(t, state, "test.d2", "a.style.fill: red\n")
+
+	if response := state.PrepareRename(1, uri, lsp.Position{Line: 0, Character: 4}); response.Result != nil {
+		t.Errorf("PrepareRename() = %+v, want nil", response.Result)
+	}
+}
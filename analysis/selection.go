@@ -0,0 +1,53 @@
+package analysis
+
+import (
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+)
+
+// selectionRanges returns the ranges enclosing position, from the outermost to the
+// innermost. Maps are also selected without their braces, when that still holds
+// position.
+func selectionRanges(ast *d2ast.Map, position lsp.Position) []lsp.Range {
+	ranges := []lsp.Range{}
+	add := func(r lsp.Range) {
+		if len(ranges) == 0 || ranges[len(ranges)-1] != r {
+			ranges = append(ranges, r)
+		}
+	}
+
+	for _, node := range nodesAtPosition(ast, position) {
+		add(toLspRange(node.GetRange()))
+		if m, ok := node.(*d2ast.Map); ok && !m.IsFileMap() && len(m.Nodes) > 0 {
+			body := m.Nodes[0].Unbox().GetRange()
+			body.End = m.Nodes[len(m.Nodes)-1].Unbox().GetRange().End
+			if rangeContains(body, position) {
+				add(toLspRange(body))
+			}
+		}
+	}
+	return ranges
+}
+
+func (s *State) SelectionRanges(id any, uri lsp.DocumentURI, positions []lsp.Position) lsp.SelectionRangeResponse {
+	response := lsp.SelectionRangeResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.SelectionRange{},
+	}
+
+	document := s.Documents[uri]
+	for _, position := range positions {
+		var selection *lsp.SelectionRange
+		if document.AST != nil {
+			for _, r := range selectionRanges(document.AST, position) {
+				selection = &lsp.SelectionRange{Range: r, Parent: selection}
+			}
+		}
+		// Each position needs a result, positions outside of any node select nothing.
+		if selection == nil {
+			selection = &lsp.SelectionRange{Range: emptyRange(position)}
+		}
+		response.Result = append(response.Result, *selection)
+	}
+	return response
+}
@@ -0,0 +1,55 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestSelectionRanges(t *testing.T) {
+	state := newTestState(t)
+	uri := openTestDocument(t, state, "test.d2", "x\nbackend: {\n  api.handler: Handler\n  db\n}\n")
+
+	r := func(startLine, startChar, endLine, endChar int) lsp.Range {
+		return lsp.Range{
+			Start: lsp.Position{Line: startLine, Character: startChar},
+			End:   lsp.Position{Line: endLine, Character: endChar},
+		}
+	}
+	positions := []lsp.Position{{Line: 2, Character: 7}, {Line: 1, Character: 9}, {Line: 9, Character: 0}}
+	got := state.SelectionRanges(1, uri, positions).Result
+
+	// Flatten the chains of parents, from the innermost range outwards.
+	flatten := func(selection lsp.SelectionRange) []lsp.Range {
+		ranges := []lsp.Range{}
+		for s := &selection; s != nil; s = s.Parent {
+			ranges = append(ranges, s.Range)
+		}
+		return ranges
+	}
+	want := [][]lsp.Range{
+		{
+			r(2, 6, 2, 13), // handler
+			r(2, 2, 2, 13), // api.handler
+			r(2, 2, 2, 22), // api.handler: Handler
+			r(2, 2, 3, 4),  // map body
+			r(1, 9, 4, 1),  // map
+			r(1, 0, 4, 1),  // backend
+			r(0, 0, 5, 0),  // board
+		},
+		{
+			r(1, 9, 4, 1), // map, its body doesn't hold the brace
+			r(1, 0, 4, 1), // backend
+			r(0, 0, 5, 0), // board
+		},
+		{r(9, 0, 9, 0)},
+	}
+	gotRanges := [][]lsp.Range{}
+	for _, selection := range got {
+		gotRanges = append(gotRanges, flatten(selection))
+	}
+	if diff := cmp.Diff(want, gotRanges); diff != "" {
+		t.Errorf("SelectionRanges() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,352 @@
+package analysis
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2ir"
+)
+
+// semanticTokensCache holds the last tokens sent for a document, which deltas are
+// computed against.
+type semanticTokensCache struct {
+	resultID int
+	data     []int
+}
+
+type semanticToken struct {
+	line      int
+	start     int
+	length    int
+	tokenType lsp.SemanticTokenType
+	modifiers int
+}
+
+// tokenScope is what the keys of a map declare.
+type tokenScope int
+
+const (
+	scopeShapes tokenScope = iota
+	scopeStyle
+	scopeVars
+	scopeClasses
+	scopeBoards
+)
+
+// tokenizer classifies the strings of a document. Keys are classified from the AST
+// since compiling replaces substitutions, the IR is only used to tell which
+// mentions of a shape declare it.
+type tokenizer struct {
+	text string
+	// Ranges of the key path segments mentioning a field first.
+	declarations map[d2ast.Range]bool
+	tokens       []semanticToken
+}
+
+func newTokenizer(text string, ir *d2ir.Map) *tokenizer {
+	t := &tokenizer{text: text, declarations: map[d2ast.Range]bool{}}
+	if ir != nil {
+		walkIR(ir, func(node d2ir.Node) {
+			if field, ok := node.(*d2ir.Field); ok && len(field.References) > 0 && field.References[0].String != nil {
+				r := field.References[0].String.GetRange()
+				// Only positions are compared, the document may be parsed under another path.
+				r.Path = ""
+				t.declarations[r] = true
+			}
+		})
+	}
+	return t
+}
+
+// add records a token over r, tokens can't span several lines.
+func (t *tokenizer) add(r d2ast.Range, tokenType lsp.SemanticTokenType, modifiers int) {
+	if r.Start.Line != r.End.Line || r.End.Column <= r.Start.Column {
+		return
+	}
+	t.tokens = append(t.tokens, semanticToken{
+		line:      r.Start.Line,
+		start:     r.Start.Column,
+		length:    r.End.Column - r.Start.Column,
+		tokenType: tokenType,
+		modifiers: modifiers,
+	})
+}
+
+func (t *tokenizer) shape(segment *d2ast.StringBox) {
+	r := segment.Unbox().GetRange()
+	modifiers := 0
+	if t.declarations[d2ast.Range{Start: r.Start, End: r.End}] {
+		modifiers = lsp.SemanticTokenDeclaration
+	}
+	t.add(r, lsp.SemanticTokenShape, modifiers)
+}
+
+// keyPath classifies the segments of a key in scope, returning the scope of its
+// value along with its last segment.
+func (t *tokenizer) keyPath(path []*d2ast.StringBox, scope tokenScope) (tokenScope, string) {
+	name := ""
+	for _, segment := range path {
+		s := segment.Unbox()
+		r := s.GetRange()
+		name = s.ScalarString()
+		_, reserved := d2ast.ReservedKeywords[name]
+		reserved = reserved && s.IsUnquoted()
+
+		switch scope {
+		case scopeStyle:
+			t.add(r, lsp.SemanticTokenAttribute, 0)
+		case scopeVars:
+			t.add(r, lsp.SemanticTokenVariable, lsp.SemanticTokenDeclaration)
+		case scopeClasses:
+			// The rest of the key sets attributes of the class.
+			t.add(r, lsp.SemanticTokenClass, lsp.SemanticTokenDeclaration)
+			scope = scopeShapes
+		case scopeBoards:
+			t.add(r, lsp.SemanticTokenBoard, lsp.SemanticTokenDeclaration)
+			scope = scopeShapes
+		case scopeShapes:
+			if !reserved {
+				t.shape(segment)
+				continue
+			}
+			t.add(r, lsp.SemanticTokenKeyword, 0)
+			switch name {
+			case "style":
+				scope = scopeStyle
+			case "vars":
+				scope = scopeVars
+			case "classes":
+				scope = scopeClasses
+			case "layers", "scenarios", "steps":
+				scope = scopeBoards
+			}
+		}
+	}
+	return scope, name
+}
+
+// operator classifies the arrow between the ends of edge, which the AST doesn't
+// give a range for. Edges still being typed lack an end and are skipped.
+func (t *tokenizer) operator(edge *d2ast.Edge) {
+	if edge.Src == nil || edge.Dst == nil {
+		return
+	}
+	start, end := edge.Src.Range.End, edge.Dst.Range.Start
+	if start.Line != end.Line {
+		return
+	}
+	line := lineAt(t.text, start.Line)
+	between := line[byteIndex(line, start.Column):byteIndex(line, end.Column)]
+	arrow := strings.TrimSpace(between)
+	start.Column += utf16Len(between[:strings.Index(between, arrow)])
+	end.Column = start.Column + utf16Len(arrow)
+	t.add(d2ast.Range{Start: start, End: end}, lsp.SemanticTokenOperator, 0)
+}
+
+// value classifies a scalar, which is a label when the key sets one. Substitutions
+// are classified wherever they appear.
+func (t *tokenizer) value(value d2ast.Scalar, label bool) {
+	var parts []d2ast.InterpolationBox
+	switch value := value.(type) {
+	case *d2ast.UnquotedString:
+		parts = value.Value
+	case *d2ast.DoubleQuotedString:
+		parts = value.Value
+	case nil:
+		return
+	}
+	substituted := false
+	for _, part := range parts {
+		if part.Substitution != nil {
+			substituted = true
+			t.add(part.Substitution.Range, lsp.SemanticTokenVariable, 0)
+		}
+	}
+	if label && !substituted {
+		t.add(value.GetRange(), lsp.SemanticTokenLabel, 0)
+	}
+}
+
+func (t *tokenizer) key(key *d2ast.Key, scope tokenScope) {
+	valueScope, name := scope, ""
+	label := false
+	if key.Key != nil {
+		// The key prefix of edges names their container.
+		valueScope, name = t.keyPath(key.Key.Path, scope)
+	}
+	if len(key.Edges) > 0 {
+		for _, edge := range key.Edges {
+			if edge.Src != nil {
+				for _, segment := range edge.Src.Path {
+					t.shape(segment)
+				}
+			}
+			t.operator(edge)
+			if edge.Dst != nil {
+				for _, segment := range edge.Dst.Path {
+					t.shape(segment)
+				}
+			}
+		}
+		valueScope, name = scopeShapes, ""
+		label = true
+		if key.EdgeKey != nil {
+			valueScope, name = t.keyPath(key.EdgeKey.Path, scopeShapes)
+			label = name == "label"
+		}
+	} else if key.Key != nil && valueScope == scopeShapes {
+		_, reserved := d2ast.ReservedKeywords[name]
+		label = !reserved || name == "label"
+	}
+
+	t.value(key.Primary.Unbox(), label && valueScope == scopeShapes)
+	switch value := key.Value.Unbox().(type) {
+	case *d2ast.Map:
+		t.scope(value, valueScope)
+	case *d2ast.Import:
+		t.add(value.Range, lsp.SemanticTokenImport, 0)
+	case *d2ast.Array:
+		for _, node := range value.Nodes {
+			if scalar, ok := node.Unbox().(d2ast.Scalar); ok && name == "class" && valueScope == scopeShapes {
+				t.add(scalar.GetRange(), lsp.SemanticTokenClass, 0)
+			}
+		}
+	case d2ast.Scalar:
+		if name == "class" && valueScope == scopeShapes {
+			t.add(value.GetRange(), lsp.SemanticTokenClass, 0)
+		} else {
+			t.value(value, label && valueScope == scopeShapes)
+		}
+	}
+}
+
+func (t *tokenizer) scope(m *d2ast.Map, scope tokenScope) {
+	for _, node := range m.Nodes {
+		switch {
+		case node.MapKey != nil:
+			t.key(node.MapKey, scope)
+		case node.Import != nil:
+			t.add(node.Import.Range, lsp.SemanticTokenImport, 0)
+		}
+	}
+}
+
+// documentTokens returns the tokens of the document in order.
+func (s *State) documentTokens(uri lsp.DocumentURI) []semanticToken {
+	document := s.Documents[uri]
+	if document.AST == nil {
+		return nil
+	}
+	_, ir, err := s.compileDocument(uri)
+	if err != nil {
+		ir = nil
+	}
+
+	t := newTokenizer(document.Text, ir)
+	t.scope(document.AST, scopeShapes)
+	slices.SortStableFunc(t.tokens, func(a, b semanticToken) int {
+		if a.line != b.line {
+			return a.line - b.line
+		}
+		return cmp.Compare(a.start, b.start)
+	})
+	return t.tokens
+}
+
+// encodeTokens encodes tokens relative to each other, skipping tokens overlapping
+// the previous one.
+func encodeTokens(tokens []semanticToken) []int {
+	data := []int{}
+	line, start, end := 0, 0, 0
+	for i, token := range tokens {
+		if i > 0 && token.line == line && token.start < end {
+			continue
+		}
+		deltaStart := token.start
+		if token.line == line {
+			deltaStart -= start
+		}
+		data = append(data, token.line-line, deltaStart, token.length, int(token.tokenType), token.modifiers)
+		line, start, end = token.line, token.start, token.start+token.length
+	}
+	return data
+}
+
+// cacheTokens stores the tokens sent for uri under a new result ID.
+func (s *State) cacheTokens(uri lsp.DocumentURI, data []int) string {
+	cache := s.semanticTokens[uri]
+	if cache == nil {
+		cache = &semanticTokensCache{}
+		s.semanticTokens[uri] = cache
+	}
+	cache.resultID++
+	cache.data = data
+	return strconv.Itoa(cache.resultID)
+}
+
+func (s *State) SemanticTokens(id any, uri lsp.DocumentURI) lsp.SemanticTokensResponse {
+	data := encodeTokens(s.documentTokens(uri))
+	return lsp.SemanticTokensResponse{
+		Response: lsp.NewResponse(id),
+		Result: lsp.SemanticTokens{
+			ResultID: s.cacheTokens(uri, data),
+			Data:     data,
+		},
+	}
+}
+
+func (s *State) SemanticTokensRange(id any, uri lsp.DocumentURI, rng lsp.Range) lsp.SemanticTokensResponse {
+	tokens := []semanticToken{}
+	for _, token := range s.documentTokens(uri) {
+		position := lsp.Position{Line: token.line, Character: token.start}
+		if comparePositions(rng.Start, position) <= 0 && comparePositions(position, rng.End) < 0 {
+			tokens = append(tokens, token)
+		}
+	}
+	return lsp.SemanticTokensResponse{
+		Response: lsp.NewResponse(id),
+		Result:   lsp.SemanticTokens{Data: encodeTokens(tokens)},
+	}
+}
+
+// SemanticTokensDelta sends the tokens that changed since the result the client
+// has, as a single edit replacing everything between the unchanged ends.
+func (s *State) SemanticTokensDelta(id any, uri lsp.DocumentURI, previousResultID string) lsp.SemanticTokensDeltaResponse {
+	response := lsp.SemanticTokensDeltaResponse{
+		Response: lsp.NewResponse(id),
+	}
+
+	cache := s.semanticTokens[uri]
+	if cache == nil || strconv.Itoa(cache.resultID) != previousResultID {
+		response.Result = s.SemanticTokens(id, uri).Result
+		return response
+	}
+
+	previous := cache.data
+	data := encodeTokens(s.documentTokens(uri))
+	prefix := 0
+	for prefix < len(previous) && prefix < len(data) && previous[prefix] == data[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(previous)-prefix && suffix < len(data)-prefix &&
+		previous[len(previous)-1-suffix] == data[len(data)-1-suffix] {
+		suffix++
+	}
+
+	delta := lsp.SemanticTokensDelta{Edits: []lsp.SemanticTokensEdit{}}
+	if prefix != len(previous) || prefix != len(data) {
+		delta.Edits = append(delta.Edits, lsp.SemanticTokensEdit{
+			Start:       prefix,
+			DeleteCount: len(previous) - prefix - suffix,
+			Data:        data[prefix : len(data)-suffix],
+		})
+	}
+	delta.ResultID = s.cacheTokens(uri, data)
+	response.Result = delta
+	return response
+}
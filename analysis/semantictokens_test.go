@@ -0,0 +1,144 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+// token is a decoded semantic token.
+type token struct {
+	Text      string
+	Type      string
+	Modifiers int
+}
+
+func decodeTokens(text string, data []int) []token {
+	lines := strings.Split(text, "\n")
+	tokens := []token{}
+	line, start := 0, 0
+	for i := 0; i+4 < len(data); i += 5 {
+		if data[i] > 0 {
+			start = 0
+		}
+		line += data[i]
+		start += data[i+1]
+		tokens = append(tokens, token{
+			Text:      lines[line][start : start+data[i+2]],
+			Type:      lsp.SemanticTokenTypes[data[i+3]],
+			Modifiers: data[i+4],
+		})
+	}
+	return tokens
+}
+
+func TestSemanticTokens(t *testing.T) {
+	text := `vars: {color: red}
+classes: {warn: {style.fill: ${color}}}
+api: API {class: warn}
+api -> db: query
+db.style.stroke: blue
+x: @lib
+layers: {
+  mobile: {app}
+}
+`
+	root := writeTestFiles(t, map[string]string{"lib.d2": "y\n"})
+	uri := lsp.File(filepath.Join(root, "test.d2"))
+	state := newTestState(t)
+	state.OpenDocument(uri, 1, text)
+
+	const declaration = lsp.SemanticTokenDeclaration
+	want := []token{
+		{"vars", "keyword", 0},
+		{"color", "variable", declaration},
+		{"classes", "keyword", 0},
+		{"warn", "class", declaration},
+		{"style", "keyword", 0},
+		{"fill", "property", 0},
+		{"${color}", "variable", 0},
+		{"api", "type", declaration},
+		{"API", "string", 0},
+		{"class", "keyword", 0},
+		{"warn", "class", 0},
+		{"api", "type", 0},
+		{"->", "operator", 0},
+		{"db", "type", declaration},
+		{"query", "string", 0},
+		{"db", "type", 0},
+		{"style", "keyword", 0},
+		{"stroke", "property", 0},
+		{"x", "type", declaration},
+		{"@lib", "decorator", 0},
+		{"layers", "keyword", 0},
+		{"mobile", "namespace", declaration},
+		{"app", "type", declaration},
+	}
+	full := state.SemanticTokens(1, uri).Result
+	if diff := cmp.Diff(want, decodeTokens(text, full.Data)); diff != "" {
+		t.Errorf("SemanticTokens() mismatch (-want +got):\n%s", diff)
+	}
+
+	rng := lsp.Range{Start: lsp.Position{Line: 3}, End: lsp.Position{Line: 4}}
+	got := state.SemanticTokensRange(2, uri, rng).Result
+	if diff := cmp.Diff(want[11:15], decodeTokens(text, got.Data)); diff != "" {
+		t.Errorf("SemanticTokensRange() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSemanticTokensDelta(t *testing.T) {
+	text := "a -> b\n"
+	state := newTestState(t)
+	uri := openTestDocument(t, state, "test.d2", text)
+	full := state.SemanticTokens(1, uri).Result
+
+	response := state.SemanticTokensDelta(2, uri, full.ResultID)
+	delta, ok := response.Result.(lsp.SemanticTokensDelta)
+	if !ok || len(delta.Edits) != 0 {
+		t.Fatalf("SemanticTokensDelta() = %+v, want no edits", response.Result)
+	}
+
+	text = "a -> b\nc\n"
+	state.UpdateDocument(uri, 2, text)
+	response = state.SemanticTokensDelta(3, uri, delta.ResultID)
+	delta, ok = response.Result.(lsp.SemanticTokensDelta)
+	if !ok {
+		t.Fatalf("SemanticTokensDelta() = %+v, want a delta", response.Result)
+	}
+	data := append([]int{}, full.Data...)
+	for _, edit := range delta.Edits {
+		data = append(data[:edit.Start], append(edit.Data, data[edit.Start+edit.DeleteCount:]...)...)
+	}
+	if diff := cmp.Diff(state.SemanticTokens(4, uri).Result.Data, data); diff != "" {
+		t.Errorf("edited tokens mismatch (-want +got):\n%s", diff)
+	}
+
+	// Unknown results get every token again.
+	response = state.SemanticTokensDelta(5, uri, "unknown")
+	if _, ok := response.Result.(lsp.SemanticTokens); !ok {
+		t.Errorf("SemanticTokensDelta() = %+v, want full tokens", response.Result)
+	}
+}
+
+func TestSemanticTokensIncompleteEdges(t *testing.T) {
+	tests := []string{
+		"a ->",
+		"a -> ",
+		"-> b",
+		"a -> b -> ",
+		"a\n<- b",
+	}
+
+	for _, text := range tests {
+		t.Run(text, func(t *testing.T) {
+			state := newTestState(t)
+			uri := openTestDocument(t, state, "test.d2", text)
+			full := state.SemanticTokens(1, uri).Result
+			state.SemanticTokensDelta(2, uri, full.ResultID)
+			state.SemanticTokensRange(3, uri, lsp.Range{End: lsp.Position{Line: 2}})
+		})
+	}
+}
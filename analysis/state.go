@@ -14,12 +14,18 @@ import (
 	"oss.terrastruct.com/d2/d2lib"
 	"oss.terrastruct.com/d2/d2lsp"
 	"oss.terrastruct.com/d2/d2parser"
+	"oss.terrastruct.com/d2/lib/textmeasure"
 )
 
 type State struct {
 	Documents        map[lsp.DocumentURI]Document
 	WorkspaceFolders map[lsp.URI]Workspace
 	logger           *log.Logger
+	ruler            *textmeasure.Ruler
+	hovers           map[lsp.DocumentURI]*hoverCache
+	semanticTokens   map[lsp.DocumentURI]*semanticTokensCache
+	outlines         map[string][]lsp.DocumentSymbol
+	options          *lsp.InitializationOptions
 }
 
 type Workspace struct {
@@ -28,19 +34,34 @@ type Workspace struct {
 }
 
 type Document struct {
-	Text   string
-	AST    *d2ast.Map
-	Errors []d2ast.Error
+	Version int
+	Text    string
+	AST     *d2ast.Map
+	Errors  []d2ast.Error
 }
 
 func NewState(logger *log.Logger) State {
+	ruler, err := textmeasure.NewRuler()
+	if err != nil {
+		logger.Printf("could not create text ruler: %v", err)
+	}
+
 	return State{
 		Documents:        map[lsp.DocumentURI]Document{},
 		WorkspaceFolders: map[lsp.URI]Workspace{},
 		logger:           logger,
+		ruler:            ruler,
+		hovers:           map[lsp.DocumentURI]*hoverCache{},
+		semanticTokens:   map[lsp.DocumentURI]*semanticTokensCache{},
+		outlines:         map[string][]lsp.DocumentSymbol{},
+		options:          &lsp.InitializationOptions{},
 	}
 }
 
+func (s *State) SetInitializationOptions(options lsp.InitializationOptions) {
+	*s.options = options
+}
+
 func (s *State) AddWorkspaceFolders(folders []lsp.WorkspaceFolder) {
 	for _, folder := range folders {
 		folderPaths := findFilesByExt(folder.URI.Filename(), ".d2")
@@ -59,27 +80,34 @@ func (s *State) RemoveWorkspaceFolders(folders []lsp.WorkspaceFolder) {
 	}
 }
 
-func (s *State) OpenDocument(uri lsp.DocumentURI, text string) []lsp.Diagnostic {
+func (s *State) OpenDocument(uri lsp.DocumentURI, version int, text string) []lsp.Diagnostic {
 	ctx := context.Background()
-	document := parseDocument(ctx, text)
+	document := parseDocument(ctx, version, text)
 	s.Documents[uri] = document
+	delete(s.outlines, uri.Filename())
 
-	return getDiagnosticsFromAST(document.Errors)
+	return s.documentDiagnostics(uri)
 }
 
-func (s *State) UpdateDocument(uri lsp.DocumentURI, text string) []lsp.Diagnostic {
+func (s *State) UpdateDocument(uri lsp.DocumentURI, version int, text string) []lsp.Diagnostic {
 	ctx := context.Background()
-	document := parseDocument(ctx, text)
+	document := parseDocument(ctx, version, text)
 	s.Documents[uri] = document
+	delete(s.outlines, uri.Filename())
 
-	return getDiagnosticsFromAST(document.Errors)
+	return s.documentDiagnostics(uri)
 }
 
 func (s *State) RemoveDocument(uri lsp.DocumentURI) {
 	delete(s.Documents, uri)
+	delete(s.hovers, uri)
+	delete(s.semanticTokens, uri)
+	delete(s.outlines, uri.Filename())
 }
 
 func (s *State) UpdateFile(path string, event lsp.FileChangeType) {
+	delete(s.outlines, path)
+
 	// TODO: This is inefficient and doesn't scale well with many workspace folders.
 	// A better approach would be to find the parent workspace folder directly from the file's path
 	// instead of iterating through all of them. This could be done by iterating up the file's
@@ -91,6 +119,9 @@ func (s *State) UpdateFile(path string, event lsp.FileChangeType) {
 
 		switch event {
 		case lsp.Created:
+			if slices.Contains(workspace.Files, path) {
+				continue
+			}
 			workspace.Files = append(workspace.Files, path)
 			s.WorkspaceFolders[uri] = workspace
 			s.logger.Printf("added %s to %s", path, workspace.Name)
@@ -108,39 +139,45 @@ func (s *State) UpdateFile(path string, event lsp.FileChangeType) {
 }
 
 func (s *State) Hover(id any, uri lsp.DocumentURI, position lsp.Position) lsp.HoverResponse {
-	document := s.Documents[uri]
-	node := getNodeUnderCursor(*document.AST, position)
+	response := lsp.HoverResponse{
+		Response: lsp.NewResponse(id),
+	}
 
-	contents := ""
-	if node != nil {
-		contents = (*node).GetRange().String()
+	document, ok := s.Documents[uri]
+	if !ok {
+		return response
 	}
 
-	return lsp.HoverResponse{
-		Response: lsp.NewResponse(id),
-		Result: lsp.HoverResult{
-			Contents: contents,
-		},
+	cache := s.compileHover(uri)
+	ast, ir := cache.ast, cache.ir
+	if cache.err != nil {
+		// Variables can still be looked up in the document as parsed, which is how
+		// undefined ones are reported.
+		ast, ir = s.parseFile(uri.Filename()), nil
 	}
-}
 
-func (s *State) Definition(id any, uri lsp.DocumentURI, position lsp.Position) lsp.DefinitionResponse {
-	return lsp.DefinitionResponse{
-		Response: lsp.NewResponse(id),
-		Result: lsp.Location{
-			URI: uri,
-			Range: lsp.Range{
-				Start: lsp.Position{
-					Line:      position.Line - 1,
-					Character: 0,
-				},
-				End: lsp.Position{
-					Line:      position.Line - 1,
-					Character: 0,
-				},
+	sections := []string{}
+	if variable := s.hoverVariable(document, ast, ir, position); variable != "" {
+		sections = append(sections, variable)
+	}
+	if ir != nil {
+		if provenance := s.hoverStyleProvenance(uri, ast, ir, position); provenance != "" {
+			sections = append(sections, provenance)
+		}
+		if thumbnail := s.hoverThumbnail(cache, position); thumbnail != "" {
+			sections = append(sections, thumbnail)
+		}
+	}
+	if len(sections) > 0 {
+		response.Result = &lsp.HoverResult{
+			Contents: lsp.MarkupContent{
+				Kind:  lsp.Markdown,
+				Value: strings.Join(sections, "\n\n---\n\n"),
 			},
-		},
+		}
 	}
+
+	return response
 }
 
 func (s *State) ImportCompletion(id any, uri lsp.DocumentURI, position lsp.Position) lsp.CompletionResponse {
@@ -237,13 +274,14 @@ func getDiagnosticsFromAST(errors []d2ast.Error) []lsp.Diagnostic {
 				},
 			},
 			Severity: lsp.Error,
+			Code:     diagnosticCode(err.Message),
 		})
 	}
 
 	return diagnostics
 }
 
-func parseDocument(ctx context.Context, text string) Document {
+func parseDocument(ctx context.Context, version int, text string) Document {
 	ast, err := d2lib.Parse(ctx, text, &d2lib.CompileOptions{
 		UTF16Pos: true,
 	})
@@ -254,25 +292,13 @@ func parseDocument(ctx context.Context, text string) Document {
 	}
 
 	return Document{
-		Text:   text,
-		AST:    ast,
-		Errors: errors,
+		Version: version,
+		Text:    text,
+		AST:     ast,
+		Errors:  errors,
 	}
 }
 
-func getNodeUnderCursor(ast d2ast.Map, position lsp.Position) *d2ast.MapNode {
-	for _, nodeBox := range ast.Nodes {
-		node := nodeBox.Unbox()
-		nodeRange := node.GetRange()
-		if position.Line >= nodeRange.Start.Line && position.Line <= nodeRange.End.Line &&
-			position.Character >= nodeRange.Start.Column && position.Character <= nodeRange.End.Column {
-			return &node
-		}
-	}
-
-	return nil
-}
-
 func findFilesByExt(root, ext string) []string {
 	files := []string{}
 	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
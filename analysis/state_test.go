@@ -0,0 +1,48 @@
+package analysis_test
+
+import (
+	"io"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ram02z/d2-language-server/analysis"
+	"github.com/ram02z/d2-language-server/log"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func newTestState(t *testing.T) analysis.State {
+	t.Helper()
+	return analysis.NewState(&log.Logger{Logger: stdlog.New(io.Discard, "", 0)})
+}
+
+// writeTestFiles writes files into a temporary directory and returns its path.
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	writeTestFilesIn(t, root, files)
+	return root
+}
+
+// writeTestFilesIn writes files into root.
+func writeTestFilesIn(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, text := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// openTestDocument opens text as a document at name in a temporary directory.
+func openTestDocument(t *testing.T, state analysis.State, name, text string) lsp.DocumentURI {
+	t.Helper()
+	uri := lsp.File(filepath.Join(t.TempDir(), name))
+	state.OpenDocument(uri, 1, text)
+	return uri
+}
@@ -0,0 +1,253 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2ir"
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+// keyLabel returns the label key sets, if any.
+func keyLabel(key *d2ast.Key) string {
+	if primary := key.Primary.Unbox(); primary != nil {
+		return primary.ScalarString()
+	}
+	if scalar := key.Value.ScalarBox().Unbox(); scalar != nil {
+		return scalar.ScalarString()
+	}
+	return ""
+}
+
+// objectPath returns the segments of path naming an object, before any attribute
+// like style.
+func objectPath(path *d2ast.KeyPath) []*d2ast.StringBox {
+	for i, segment := range path.Path {
+		if _, reserved := d2ast.ReservedKeywords[segment.Unbox().ScalarString()]; reserved && segment.Unbox().IsUnquoted() {
+			return path.Path[:i]
+		}
+	}
+	return path.Path
+}
+
+// outline holds what the document symbols need from the compiled document, both
+// maps are empty when it doesn't compile.
+type outline struct {
+	keys map[*d2ast.Key]d2ir.Node
+	// Fields by the key path segment declaring them first.
+	declared map[d2ast.String]*d2ir.Field
+}
+
+func newOutline(ir *d2ir.Map) outline {
+	o := outline{
+		keys:     map[*d2ast.Key]d2ir.Node{},
+		declared: map[d2ast.String]*d2ir.Field{},
+	}
+	if ir == nil {
+		return o
+	}
+	o.keys = nodesByKey(ir)
+	walkIR(ir, func(node d2ir.Node) {
+		if field, ok := node.(*d2ir.Field); ok && len(field.References) > 0 {
+			o.declared[field.References[0].String] = field
+		}
+	})
+	return o
+}
+
+// boardSymbols returns the boards declared in the map of a board keyword like
+// layers.
+func boardSymbols(keyword string, m *d2ast.Map, o outline) []lsp.DocumentSymbol {
+	symbols := []lsp.DocumentSymbol{}
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == nil || !isPlainKey(key) || key.Value.Map == nil {
+			continue
+		}
+		symbols = append(symbols, boardSymbol(keyword, key, key.Key, o))
+	}
+	return symbols
+}
+
+// boardSymbol describes the board key declares, with the kind of board as detail.
+func boardSymbol(keyword string, key *d2ast.Key, name *d2ast.KeyPath, o outline) lsp.DocumentSymbol {
+	return lsp.DocumentSymbol{
+		Name:           d2format.Format(name),
+		Detail:         strings.TrimSuffix(keyword, "s"),
+		Kind:           lsp.SymbolKindModule,
+		Range:          toLspRange(key.Range),
+		SelectionRange: pathRange(name.Path),
+		Children:       documentSymbols(key.Value.Map, o),
+	}
+}
+
+// pathRange returns the range covering the segments of path.
+func pathRange(path []*d2ast.StringBox) lsp.Range {
+	r := toLspRange(path[0].Unbox().GetRange())
+	r.End = toLspRange(path[len(path)-1].Unbox().GetRange()).End
+	return r
+}
+
+// declarationSymbols returns a symbol for each key of classes or vars, named after
+// the keys rather than the objects they would declare. Keys of classes set
+// attributes past their first segment, so classes are named after it alone.
+func declarationSymbols(m *d2ast.Map, kind lsp.SymbolKind, nested bool) []lsp.DocumentSymbol {
+	symbols := []lsp.DocumentSymbol{}
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == nil || !isPlainKey(key) {
+			continue
+		}
+		path, detail := key.Key.Path, keyLabel(key)
+		if !nested && len(path) > 1 {
+			path, detail = path[:1], ""
+		}
+		symbol := lsp.DocumentSymbol{
+			Name:           d2format.Format(&d2ast.KeyPath{Path: path}),
+			Detail:         detail,
+			Kind:           kind,
+			Range:          toLspRange(key.Range),
+			SelectionRange: pathRange(path),
+		}
+		if nested && key.Value.Map != nil {
+			symbol.Children = declarationSymbols(key.Value.Map, kind, nested)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// edgeSymbol describes the connections declared by key, named after them as written.
+func edgeSymbol(key *d2ast.Key) lsp.DocumentSymbol {
+	edges := &d2ast.Key{Key: key.Key, Edges: key.Edges, EdgeIndex: key.EdgeIndex}
+	selection := toLspRange(key.Edges[0].Range)
+	selection.End = toLspRange(key.Edges[len(key.Edges)-1].Range).End
+	return lsp.DocumentSymbol{
+		Name:           d2format.Format(edges),
+		Detail:         keyLabel(key),
+		Kind:           lsp.SymbolKindEvent,
+		Range:          toLspRange(key.Range),
+		SelectionRange: selection,
+	}
+}
+
+// documentSymbols returns the outline of m. Attributes are left out, except for
+// classes and vars, which are listed with what they declare.
+func documentSymbols(m *d2ast.Map, o outline) []lsp.DocumentSymbol {
+	symbols := []lsp.DocumentSymbol{}
+	for _, node := range m.Nodes {
+		key := node.MapKey
+		if key == nil || key.Ampersand || key.NotAmpersand {
+			continue
+		}
+		if len(key.Edges) > 0 {
+			if key.EdgeKey == nil {
+				symbols = append(symbols, edgeSymbol(key))
+			}
+			continue
+		}
+		if key.Key == nil {
+			continue
+		}
+
+		path := key.Key.Path
+		first := path[0].Unbox().ScalarString()
+		if _, ok := d2ast.BoardKeywords[first]; ok && key.Value.Map != nil {
+			switch len(path) {
+			case 1:
+				symbols = append(symbols, boardSymbols(first, key.Value.Map, o)...)
+			case 2:
+				symbols = append(symbols, boardSymbol(first, key, &d2ast.KeyPath{Path: path[1:]}, o))
+			}
+			continue
+		}
+		if len(path) == 1 && key.Value.Map != nil && (first == "classes" || first == "vars") {
+			kind, nested := lsp.SymbolKindClass, false
+			if first == "vars" {
+				kind, nested = lsp.SymbolKindVariable, true
+			}
+			symbols = append(symbols, lsp.DocumentSymbol{
+				Name:           first,
+				Kind:           lsp.SymbolKindNamespace,
+				Range:          toLspRange(key.Range),
+				SelectionRange: pathRange(path),
+				Children:       declarationSymbols(key.Value.Map, kind, nested),
+			})
+			continue
+		}
+
+		objectPath := objectPath(key.Key)
+		if len(objectPath) == 0 {
+			continue
+		}
+		if len(objectPath) < len(path) {
+			// Keys setting attributes are only listed when they declare the object,
+			// like a.shape: circle.
+			field := o.declared[objectPath[len(objectPath)-1].Unbox()]
+			if field == nil {
+				continue
+			}
+			symbols = append(symbols, lsp.DocumentSymbol{
+				Name:           d2format.Format(&d2ast.KeyPath{Path: objectPath}),
+				Kind:           symbolKind(field),
+				Range:          toLspRange(key.Range),
+				SelectionRange: pathRange(objectPath),
+			})
+			continue
+		}
+
+		symbol := lsp.DocumentSymbol{
+			Name:           d2format.Format(key.Key),
+			Detail:         keyLabel(key),
+			Kind:           lsp.SymbolKindObject,
+			Range:          toLspRange(key.Range),
+			SelectionRange: pathRange(path),
+		}
+		if key.Value.Map != nil {
+			if children := documentSymbols(key.Value.Map, o); len(children) > 0 {
+				symbol.Children = children
+			}
+		}
+		if field, ok := o.keys[key].(*d2ir.Field); ok {
+			symbol.Kind = symbolKind(field)
+		} else if len(symbol.Children) > 0 {
+			symbol.Kind = lsp.SymbolKindNamespace
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// fileSymbols returns the outline of the file at path. It is still given when the
+// file doesn't compile, only with less precise kinds.
+func (s *State) fileSymbols(path string) []lsp.DocumentSymbol {
+	ast, ir, err := s.compileFile(path)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", path, err)
+		text, err := s.fileText(path)
+		if err != nil {
+			return nil
+		}
+		ast, _ = d2parser.Parse(path, strings.NewReader(text), &d2parser.ParseOptions{
+			UTF16Pos: true,
+		})
+		ir = nil
+	}
+	if ast == nil {
+		return nil
+	}
+	return documentSymbols(ast, newOutline(ir))
+}
+
+func (s *State) DocumentSymbols(id any, uri lsp.DocumentURI) lsp.DocumentSymbolResponse {
+	response := lsp.DocumentSymbolResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.DocumentSymbol{},
+	}
+	if symbols := s.fileSymbols(uri.Filename()); symbols != nil {
+		response.Result = symbols
+	}
+	return response
+}
@@ -0,0 +1,70 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestDocumentSymbols(t *testing.T) {
+	state := newTestState(t)
+	uri := openTestDocument(t, state, "test.d2", `vars: {
+  color: red
+}
+classes: {
+  warn.style.fill: ${color}
+}
+backend: Backend {
+  db.shape: cylinder
+  api -> db: query
+}
+users: {shape: sql_table}
+backend.style.fill: blue
+layers: {
+  mobile: {
+    app
+  }
+}
+`)
+
+	r := func(startLine, startChar, endLine, endChar int) lsp.Range {
+		return lsp.Range{
+			Start: lsp.Position{Line: startLine, Character: startChar},
+			End:   lsp.Position{Line: endLine, Character: endChar},
+		}
+	}
+	want := []lsp.DocumentSymbol{
+		{
+			Name: "vars", Kind: lsp.SymbolKindNamespace, Range: r(0, 0, 2, 1), SelectionRange: r(0, 0, 0, 4),
+			Children: []lsp.DocumentSymbol{
+				{Name: "color", Detail: "red", Kind: lsp.SymbolKindVariable, Range: r(1, 2, 1, 12), SelectionRange: r(1, 2, 1, 7)},
+			},
+		},
+		{
+			Name: "classes", Kind: lsp.SymbolKindNamespace, Range: r(3, 0, 5, 1), SelectionRange: r(3, 0, 3, 7),
+			Children: []lsp.DocumentSymbol{
+				{Name: "warn", Kind: lsp.SymbolKindClass, Range: r(4, 2, 4, 27), SelectionRange: r(4, 2, 4, 6)},
+			},
+		},
+		{
+			Name: "backend", Detail: "Backend", Kind: lsp.SymbolKindNamespace, Range: r(6, 0, 9, 1), SelectionRange: r(6, 0, 6, 7),
+			Children: []lsp.DocumentSymbol{
+				{Name: "db", Kind: lsp.SymbolKindObject, Range: r(7, 2, 7, 20), SelectionRange: r(7, 2, 7, 4)},
+				{Name: "api -> db", Detail: "query", Kind: lsp.SymbolKindEvent, Range: r(8, 2, 8, 18), SelectionRange: r(8, 2, 8, 11)},
+			},
+		},
+		{Name: "users", Kind: lsp.SymbolKindStruct, Range: r(10, 0, 10, 25), SelectionRange: r(10, 0, 10, 5)},
+		{
+			Name: "mobile", Detail: "layer", Kind: lsp.SymbolKindModule, Range: r(13, 2, 15, 3), SelectionRange: r(13, 2, 13, 8),
+			Children: []lsp.DocumentSymbol{
+				{Name: "app", Kind: lsp.SymbolKindObject, Range: r(14, 4, 14, 7), SelectionRange: r(14, 4, 14, 7)},
+			},
+		},
+	}
+
+	got := state.DocumentSymbols(1, uri).Result
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DocumentSymbols() mismatch (-want +got):\n%s", diff)
+	}
+}
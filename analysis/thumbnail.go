@@ -0,0 +1,188 @@
+package analysis
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2graph"
+	"oss.terrastruct.com/d2/d2ir"
+	"oss.terrastruct.com/d2/d2layouts/d2dagrelayout"
+	"oss.terrastruct.com/d2/d2lib"
+	"oss.terrastruct.com/d2/d2renderers/d2svg"
+	d2log "oss.terrastruct.com/d2/lib/log"
+	"oss.terrastruct.com/util-go/go2"
+)
+
+const (
+	// Rendering is skipped for targets with more fields than this, layout gets slow.
+	thumbnailMaxFields = 300
+	// Larger SVGs are dropped rather than embedded in the hover.
+	thumbnailMaxBytes = 512 * 1024
+	thumbnailMaxWidth = 480
+	thumbnailPadding  = 10
+)
+
+// hoverCache holds the last compile of a document for hovers and inlay hints,
+// along with the thumbnails rendered from it. It stays valid while neither the document nor any
+// file it imports changes.
+type hoverCache struct {
+	version int
+	imports map[string]string
+	ast     *d2ast.Map
+	ir      *d2ir.Map
+	err     error
+	images  map[string]string
+}
+
+// compileHover compiles the document for a hover, unless the last compile is still
+// valid.
+func (s *State) compileHover(uri lsp.DocumentURI) *hoverCache {
+	version := s.Documents[uri].Version
+	imports := s.importContents(uri.Filename())
+	if cache := s.hovers[uri]; cache != nil && cache.version == version && maps.Equal(cache.imports, imports) {
+		return cache
+	}
+
+	ast, ir, err := s.compileDocument(uri)
+	if err != nil {
+		s.logger.Printf("could not compile %s: %v", uri, err)
+	}
+	cache := &hoverCache{
+		version: version,
+		imports: imports,
+		ast:     ast,
+		ir:      ir,
+		err:     err,
+		images:  map[string]string{},
+	}
+	s.hovers[uri] = cache
+	return cache
+}
+
+// hoverThumbnail renders the container or board under the cursor as a markdown
+// image. Plain shapes get none, rendering runs on the request path and laying out
+// the whole board around them would hold up every hover.
+func (s *State) hoverThumbnail(cache *hoverCache, position lsp.Position) string {
+	if s.ruler == nil {
+		return ""
+	}
+	ast, ir := cache.ast, cache.ir
+	var field *d2ir.Field
+	chain := nodesAtPosition(ast, position)
+	for i := len(chain) - 1; i >= 0 && field == nil; i-- {
+		if str, ok := chain[i].(d2ast.String); ok {
+			field = fieldAtString(ir, str)
+		}
+	}
+	if field == nil {
+		return ""
+	}
+
+	var key string
+	var target d2ir.Node
+	switch {
+	case isContainer(field):
+		key = formatIDA(d2ir.IDA(field))
+		target = field
+	case d2ir.NodeBoardKind(field) != "" && field.Map() != nil:
+		key = "board:" + formatIDA(d2ir.IDA(field))
+		target = field.Map()
+	default:
+		return ""
+	}
+
+	if image, ok := cache.images[key]; ok {
+		return image
+	}
+
+	image, err := s.renderThumbnail(target)
+	if err != nil {
+		s.logger.Printf("could not render thumbnail for %s: %v", key, err)
+	}
+	// Failures are cached too so that hovering doesn't retry the layout every time.
+	cache.images[key] = image
+
+	return image
+}
+
+func (s *State) renderThumbnail(target d2ir.Node) (string, error) {
+	if count := target.Map().FieldCountRecursive(); count > thumbnailMaxFields {
+		return fmt.Sprintf("_Preview skipped: %d fields exceeds the limit of %d._", count, thumbnailMaxFields), nil
+	}
+
+	source := thumbnailSource(target)
+	ctx := d2log.With(context.Background(), slog.New(slog.NewTextHandler(s.logger.Writer(), nil)))
+	renderOpts := &d2svg.RenderOpts{
+		Pad: go2.Pointer(int64(thumbnailPadding)),
+	}
+	diagram, _, err := d2lib.Compile(ctx, source, &d2lib.CompileOptions{
+		Ruler: s.ruler,
+		// Only dagre is bundled, so any configured layout engine falls back to it.
+		Layout: go2.Pointer("dagre"),
+		LayoutResolver: func(engine string) (d2graph.LayoutGraph, error) {
+			return d2dagrelayout.DefaultLayout, nil
+		},
+	}, renderOpts)
+	if err != nil {
+		return "", err
+	}
+
+	topLeft, bottomRight := diagram.BoundingBox()
+	if width := float64(bottomRight.X-topLeft.X) + 2*thumbnailPadding; width > thumbnailMaxWidth {
+		renderOpts.Scale = go2.Pointer(thumbnailMaxWidth / width)
+	}
+	svg, err := d2svg.Render(diagram, renderOpts)
+	if err != nil {
+		return "", err
+	}
+	if len(svg) > thumbnailMaxBytes {
+		return fmt.Sprintf("_Preview skipped: rendered SVG is %d KiB._", len(svg)/1024), nil
+	}
+
+	return fmt.Sprintf("![preview](data:image/svg+xml;base64,%s)", base64.StdEncoding.EncodeToString(svg)), nil
+}
+
+// thumbnailSource returns standalone D2 source for target, which is either a
+// container field or a board map.
+func thumbnailSource(target d2ir.Node) string {
+	var nodes []d2ast.MapNodeBox
+	switch target := target.(type) {
+	case *d2ir.Field:
+		// Classes and configuration live at the board root, carry them along so the
+		// container looks the same as in the full diagram.
+		board := boardRoot(target)
+		for _, name := range []string{"vars", "classes"} {
+			if field := board.GetField(d2ast.FlatUnquotedString(name)); field != nil {
+				nodes = append(nodes, d2ast.MakeMapNodeBox(field.AST().(d2ast.MapNode)))
+			}
+		}
+		nodes = append(nodes, d2ast.MakeMapNodeBox(target.AST().(d2ast.MapNode)))
+	case *d2ir.Map:
+		// Nested boards are rendered separately, leave them out of the preview.
+		for _, node := range target.AST().(*d2ast.Map).Nodes {
+			if !node.IsBoardNode() {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+
+	return d2format.Format(&d2ast.Map{
+		Range: d2ast.MakeRange(",0:0:0-1:0:0"),
+		Nodes: nodes,
+	})
+}
+
+func formatIDA(ida []d2ast.String) string {
+	parts := make([]string, len(ida))
+	for i, s := range ida {
+		parts[i] = d2format.Format(s)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,368 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ram02z/d2-language-server/lsp"
+	"oss.terrastruct.com/d2/d2ast"
+	"oss.terrastruct.com/d2/d2format"
+	"oss.terrastruct.com/d2/d2ir"
+)
+
+// variableAtPosition returns the substitution under the cursor together with the
+// vars field it resolves to.
+//
+// Compiling replaces substitutions in the AST with their values, so they are looked
+// up in the document's own AST and then matched to the compiled one by range.
+func variableAtPosition(document Document, ast *d2ast.Map, ir *d2ir.Map, keys map[*d2ast.Key]d2ir.Node, position lsp.Position) (*d2ast.Substitution, *d2ir.Field) {
+	chain := nodesAtPosition(document.AST, position)
+
+	var substitution *d2ast.Substitution
+	var key *d2ast.Key
+	for i := len(chain) - 1; i >= 0; i-- {
+		switch node := chain[i].(type) {
+		case *d2ast.Substitution:
+			if substitution == nil {
+				substitution = node
+			}
+		case *d2ast.Key:
+			if substitution != nil && key == nil {
+				key = node
+			}
+		}
+	}
+	if substitution == nil {
+		return nil, nil
+	}
+
+	// Substitutions are resolved against the vars of every enclosing map, starting
+	// from the innermost one.
+	scope := ir
+	if key != nil {
+		key = compiledKey(ast, key)
+	}
+	owner, ok := keys[key]
+	if ok {
+		scope = d2ir.ParentMap(owner)
+		if key.Value.Map != nil && rangeContains(key.Value.Map.Range, position) {
+			// Spread substitution inside the value map.
+			scope = owner.Map()
+		}
+	}
+
+	return substitution, resolveVariable(scope, owner, substitution)
+}
+
+// compiledKey returns the key in the compiled AST at the same range as key.
+func compiledKey(ast *d2ast.Map, key *d2ast.Key) *d2ast.Key {
+	for _, node := range nodesAtPosition(ast, toLspPosition(key.Range.Start)) {
+		if compiled, ok := node.(*d2ast.Key); ok &&
+			compiled.Range.Start == key.Range.Start && compiled.Range.End == key.Range.End {
+			return compiled
+		}
+	}
+	return nil
+}
+
+func resolveVariable(scope *d2ir.Map, owner d2ir.Node, substitution *d2ast.Substitution) *d2ir.Field {
+	ida := make([]d2ast.String, len(substitution.Path))
+	for i, sb := range substitution.Path {
+		ida[i] = sb.Unbox()
+	}
+
+	for m := scope; m != nil; m = d2ir.ParentMap(m) {
+		vars := m.GetField(d2ast.FlatUnquotedString("vars"))
+		if vars == nil || vars.Map() == nil {
+			continue
+		}
+		// A variable cannot be defined in terms of itself.
+		if field := vars.Map().GetField(ida...); field != nil && d2ir.Node(field) != owner {
+			return field
+		}
+	}
+
+	return nil
+}
+
+func variableValue(field *d2ir.Field) string {
+	if field.Primary() != nil {
+		return field.Primary().Value.ScalarString()
+	}
+	if field.Composite != nil {
+		return d2format.Format(field.Composite.AST())
+	}
+	return ""
+}
+
+// variableDefinition returns the range of the key that last assigned field a value.
+func variableDefinition(field *d2ir.Field) d2ast.Range {
+	ref := field.LastPrimaryRef()
+	if ref == nil {
+		ref = field.LastRef()
+	}
+	return ref.AST().GetRange()
+}
+
+// variable is what a substitution resolves to.
+type variable struct {
+	value string
+	// composite is set for maps and arrays, which are shown as D2.
+	composite  bool
+	definition d2ast.Range
+}
+
+func fieldVariable(field *d2ir.Field) *variable {
+	if field == nil {
+		return nil
+	}
+	return &variable{
+		value:      variableValue(field),
+		composite:  field.Primary() == nil,
+		definition: variableDefinition(field),
+	}
+}
+
+func keyVariable(key *d2ast.Key) *variable {
+	if key == nil {
+		return nil
+	}
+	v := &variable{definition: key.Range}
+	if primary := key.Primary.Unbox(); primary != nil {
+		v.value = primary.ScalarString()
+		return v
+	}
+	switch value := key.Value.Unbox().(type) {
+	case d2ast.Scalar:
+		v.value = value.ScalarString()
+	case *d2ast.Map, *d2ast.Array:
+		v.value = d2format.Format(value)
+		v.composite = true
+	}
+	return v
+}
+
+// astVariables looks variables up in a parsed document, for when it doesn't compile
+// and there is no IR to resolve them with. Imports inside vars and imports spread
+// into the maps around them are followed, each of them once, which is enough to
+// stop import cycles.
+type astVariables struct {
+	s *State
+	// The key holding the substitution, which can't define it.
+	owner    *d2ast.Key
+	imported map[string]bool
+}
+
+func (v *astVariables) importedMap(importer string, imp *d2ast.Import) *d2ast.Map {
+	path := importPath(importer, imp)
+	if v.imported[path] {
+		return nil
+	}
+	v.imported[path] = true
+	return v.s.parseFile(path)
+}
+
+// assignedKey returns the key last assigning ida, starting from key, which is at
+// segments in the map searched.
+func (v *astVariables) assignedKey(key *d2ast.Key, segments []*d2ast.StringBox, ida []string) *d2ast.Key {
+	if len(segments) > len(ida) {
+		return nil
+	}
+	for i, segment := range segments {
+		if segment.Unbox().ScalarString() != ida[i] {
+			return nil
+		}
+	}
+	ida = ida[len(segments):]
+	switch {
+	case len(ida) == 0:
+		if key == v.owner {
+			return nil
+		}
+		return key
+	case key.Value.Map != nil:
+		return v.mapKey(key.Value.Map, ida)
+	case key.Value.Import != nil:
+		return v.mapKey(v.importedMap(key.Range.Path, key.Value.Import), ida)
+	}
+	return nil
+}
+
+// mapKey returns the key last assigning ida in m.
+func (v *astVariables) mapKey(m *d2ast.Map, ida []string) *d2ast.Key {
+	if m == nil {
+		return nil
+	}
+	var found *d2ast.Key
+	for _, node := range m.Nodes {
+		var assigned *d2ast.Key
+		switch {
+		case node.MapKey != nil && isPlainKey(node.MapKey):
+			assigned = v.assignedKey(node.MapKey, node.MapKey.Key.Path, ida)
+		case node.Import != nil:
+			assigned = v.mapKey(v.importedMap(m.Range.Path, node.Import), ida)
+		}
+		if assigned != nil {
+			found = assigned
+		}
+	}
+	return found
+}
+
+// varsKey returns the key last assigning ida in the vars of m, including vars
+// spread into m by an import like ...@vars.
+func (v *astVariables) varsKey(m *d2ast.Map, ida []string) *d2ast.Key {
+	if m == nil {
+		return nil
+	}
+	var found *d2ast.Key
+	for _, node := range m.Nodes {
+		var assigned *d2ast.Key
+		switch key := node.MapKey; {
+		case key != nil && isPlainKey(key) && key.Key.Path[0].Unbox().ScalarString() == "vars":
+			assigned = v.assignedKey(key, key.Key.Path[1:], ida)
+		case node.Import != nil:
+			assigned = v.varsKey(v.importedMap(m.Range.Path, node.Import), ida)
+		}
+		if assigned != nil {
+			found = assigned
+		}
+	}
+	return found
+}
+
+// declaredVariable returns the substitution under the cursor in ast, resolved
+// against the vars of every enclosing map, starting from the innermost one.
+func (s *State) declaredVariable(ast *d2ast.Map, position lsp.Position) (*d2ast.Substitution, *variable) {
+	chain := nodesAtPosition(ast, position)
+	v := &astVariables{s: s, imported: map[string]bool{}}
+
+	var substitution *d2ast.Substitution
+	for i := len(chain) - 1; i >= 0; i-- {
+		switch node := chain[i].(type) {
+		case *d2ast.Substitution:
+			if substitution == nil {
+				substitution = node
+			}
+		case *d2ast.Key:
+			if substitution != nil && v.owner == nil {
+				v.owner = node
+			}
+		case *d2ast.Map:
+			if substitution == nil {
+				continue
+			}
+			if key := v.varsKey(node, substitution.IDA()); key != nil {
+				return substitution, keyVariable(key)
+			}
+		}
+	}
+	return substitution, nil
+}
+
+// substitutionAt returns the substitution under the cursor with the variable it
+// resolves to. Without IR, when the document doesn't compile, variables are looked
+// up in ast as parsed.
+func (s *State) substitutionAt(document Document, ast *d2ast.Map, ir *d2ir.Map, keys map[*d2ast.Key]d2ir.Node, position lsp.Position) (*d2ast.Substitution, *variable) {
+	if ir == nil {
+		return s.declaredVariable(ast, position)
+	}
+	substitution, field := variableAtPosition(document, ast, ir, keys, position)
+	return substitution, fieldVariable(field)
+}
+
+func (s *State) hoverVariable(document Document, ast *d2ast.Map, ir *d2ir.Map, position lsp.Position) string {
+	var keys map[*d2ast.Key]d2ir.Node
+	if ir != nil {
+		keys = nodesByKey(ir)
+	}
+	substitution, variable := s.substitutionAt(document, ast, ir, keys, position)
+	if substitution == nil {
+		return ""
+	}
+	name := strings.Join(substitution.IDA(), ".")
+	if variable == nil {
+		return fmt.Sprintf("`${%s}` is not defined", name)
+	}
+
+	definition := variable.definition
+	lang := ""
+	if variable.composite {
+		lang = "d2"
+	}
+
+	return fmt.Sprintf(
+		"`${%s}`\n```%s\n%s\n```\nDefined at [%s:%s](%s#L%d)",
+		name,
+		lang,
+		variable.value,
+		filepath.Base(definition.Path),
+		definition.Start,
+		lsp.File(definition.Path),
+		definition.Start.Line+1,
+	)
+}
+
+// relativePath returns path relative to the workspace folder holding the document
+// at uri, or to the document's directory when no folder holds it.
+func (s *State) relativePath(uri lsp.DocumentURI, path string) string {
+	root := filepath.Dir(uri.Filename())
+	for folder := range s.WorkspaceFolders {
+		if dir := folder.Filename(); strings.HasPrefix(uri.Filename(), dir+string(filepath.Separator)) && len(dir) < len(root) {
+			root = dir
+		}
+	}
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+func (s *State) InlayHints(id any, uri lsp.DocumentURI, rng lsp.Range) lsp.InlayHintResponse {
+	response := lsp.InlayHintResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.InlayHint{},
+	}
+
+	document, ok := s.Documents[uri]
+	if !ok {
+		return response
+	}
+
+	cache := s.compileHover(uri)
+	ast, ir := cache.ast, cache.ir
+	var keys map[*d2ast.Key]d2ir.Node
+	if cache.err != nil {
+		ast, ir = s.parseFile(uri.Filename()), nil
+	} else {
+		keys = nodesByKey(ir)
+	}
+	d2ast.Walk(document.AST, func(node d2ast.Node) bool {
+		substitution, ok := node.(*d2ast.Substitution)
+		if !ok {
+			return true
+		}
+		end := toLspPosition(substitution.Range.End)
+		if comparePositions(end, rng.Start) < 0 || comparePositions(end, rng.End) > 0 {
+			return false
+		}
+
+		// Look the substitution up from inside it, its edges may touch a neighbour.
+		inside := toLspPosition(substitution.Range.Start)
+		inside.Character++
+		_, variable := s.substitutionAt(document, ast, ir, keys, inside)
+		if variable == nil || variable.composite {
+			return false
+		}
+		response.Result = append(response.Result, lsp.InlayHint{
+			Position:    end,
+			Label:       "= " + variable.value,
+			Tooltip:     fmt.Sprintf("defined at %s:%s", s.relativePath(uri, variable.definition.Path), variable.definition.Start),
+			PaddingLeft: true,
+		})
+		return false
+	})
+
+	return response
+}
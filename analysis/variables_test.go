@@ -0,0 +1,151 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestHoverVariable(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"vars.d2": "brand: blue\n",
+	})
+	text := "vars: {\n  color: red\n  ...@vars\n}\na.style.fill: ${color}\nb.style.fill: ${brand}\nlayers: {\n  l: {\n    vars: {color: green}\n    c: ${color}\n  }\n}\n"
+
+	tests := []struct {
+		name     string
+		position lsp.Position
+		value    string
+		defined  string
+	}{
+		{
+			name:     "same file",
+			position: lsp.Position{Line: 4, Character: 18},
+			value:    "red",
+			defined:  "test.d2:2:3",
+		},
+		{
+			name:     "imported",
+			position: lsp.Position{Line: 5, Character: 18},
+			value:    "blue",
+			defined:  "vars.d2:1:1",
+		},
+		{
+			name:     "board override",
+			position: lsp.Position{Line: 9, Character: 9},
+			value:    "green",
+			defined:  "test.d2:9:12",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := newTestState(t)
+			uri := lsp.File(filepath.Join(root, "test.d2"))
+			state.OpenDocument(uri, 1, text)
+
+			response := state.Hover(1, uri, test.position)
+			if response.Result == nil {
+				t.Fatalf("Hover(%v) returned no result", test.position)
+			}
+			contents := response.Result.Contents.Value
+			if !strings.Contains(contents, "\n"+test.value+"\n") {
+				t.Errorf("Hover(%v) = %q, want value %q", test.position, contents, test.value)
+			}
+			if !strings.Contains(contents, "["+test.defined+"]") {
+				t.Errorf("Hover(%v) = %q, want definition %q", test.position, contents, test.defined)
+			}
+		})
+	}
+}
+
+func TestInlayHints(t *testing.T) {
+	state := newTestState(t)
+	uri := openTestDocument(t, state, "test.d2", "vars: {x: 1; y: 2}\na: ${x}\nb: ${y}\n")
+
+	response := state.InlayHints(1, uri, lsp.Range{
+		Start: lsp.Position{Line: 1, Character: 0},
+		End:   lsp.Position{Line: 1, Character: 10},
+	})
+	labels := []string{}
+	for _, hint := range response.Result {
+		labels = append(labels, hint.Label)
+	}
+	if diff := cmp.Diff([]string{"= 1"}, labels); diff != "" {
+		t.Errorf("InlayHints() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInlayHintsClosedDocument(t *testing.T) {
+	state := newTestState(t)
+	uri := lsp.File(t.TempDir() + "/test.d2")
+
+	response := state.InlayHints(1, uri, lsp.Range{End: lsp.Position{Line: 1}})
+	if diff := cmp.Diff([]lsp.InlayHint{}, response.Result); diff != "" {
+		t.Errorf("InlayHints() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVariablesWithoutCompiling(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"vars.d2":    "brand: blue\n",
+		"palette.d2": "vars: {accent: green}\n",
+	})
+	// ${missing} stops the document from compiling.
+	text := "vars: {\n  color: red\n  ...@vars\n}\na: ${missing}\nb: ${color}\nc: ${brand}\n...@palette\nd: ${accent}\n"
+	state := newTestState(t)
+	uri := lsp.File(filepath.Join(root, "test.d2"))
+	state.OpenDocument(uri, 1, text)
+
+	tests := []struct {
+		name     string
+		position lsp.Position
+		want     string
+	}{
+		{
+			name:     "undefined",
+			position: lsp.Position{Line: 4, Character: 6},
+			want:     "`${missing}` is not defined",
+		},
+		{
+			name:     "defined",
+			position: lsp.Position{Line: 5, Character: 6},
+			want:     "\nred\n",
+		},
+		{
+			name:     "imported",
+			position: lsp.Position{Line: 6, Character: 6},
+			want:     "[vars.d2:1:1]",
+		},
+		{
+			name:     "spread import",
+			position: lsp.Position{Line: 8, Character: 6},
+			want:     "[palette.d2:1:8]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := state.Hover(1, uri, test.position)
+			if response.Result == nil {
+				t.Fatalf("Hover(%v) returned no result", test.position)
+			}
+			if contents := response.Result.Contents.Value; !strings.Contains(contents, test.want) {
+				t.Errorf("Hover(%v) = %q, want %q", test.position, contents, test.want)
+			}
+		})
+	}
+
+	response := state.InlayHints(2, uri, lsp.Range{End: lsp.Position{Line: 9}})
+	labels := []string{}
+	for _, hint := range response.Result {
+		labels = append(labels, hint.Label+" "+hint.Tooltip)
+	}
+	want := []string{"= red defined at test.d2:2:3", "= blue defined at vars.d2:1:1", "= green defined at palette.d2:1:8"}
+	if diff := cmp.Diff(want, labels); diff != "" {
+		t.Errorf("InlayHints() mismatch (-want +got):\n%s", diff)
+	}
+}
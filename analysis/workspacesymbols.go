@@ -0,0 +1,133 @@
+package analysis
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+// fuzzyScore reports whether the characters of query appear in candidate in order,
+// ignoring case. Matches at the start of words and runs of matches score higher.
+func fuzzyScore(query, candidate string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	c := []rune(strings.ToLower(candidate))
+	score, i := 0, 0
+	previous := false
+	for j, r := range c {
+		if i == len(q) {
+			break
+		}
+		if r != q[i] {
+			previous = false
+			continue
+		}
+		score++
+		if previous {
+			score += 5
+		}
+		if j == 0 || !unicode.IsLetter(c[j-1]) && !unicode.IsDigit(c[j-1]) {
+			score += 3
+		}
+		previous = true
+		i++
+	}
+	return score, i == len(q)
+}
+
+// workspaceSymbol is a symbol matching the query, with its score.
+type workspaceSymbol struct {
+	lsp.WorkspaceSymbol
+	score int
+}
+
+// matchSymbols returns the symbols of the outline matching query by ID or label.
+// Edges and vars aren't listed, nor are the classes keyword itself.
+func matchSymbols(uri lsp.DocumentURI, symbols []lsp.DocumentSymbol, container, query string) []workspaceSymbol {
+	matches := []workspaceSymbol{}
+	for _, symbol := range symbols {
+		switch {
+		case symbol.Kind == lsp.SymbolKindEvent:
+			continue
+		case symbol.Kind == lsp.SymbolKindNamespace && symbol.Name == "vars":
+			continue
+		case symbol.Kind == lsp.SymbolKindNamespace && symbol.Name == "classes":
+			matches = append(matches, matchSymbols(uri, symbol.Children, symbol.Name, query)...)
+			continue
+		}
+
+		id := symbol.Name
+		if container != "" {
+			id = container + "." + symbol.Name
+		}
+		match := workspaceSymbol{
+			WorkspaceSymbol: lsp.WorkspaceSymbol{
+				Name:          symbol.Name,
+				Kind:          symbol.Kind,
+				ContainerName: container,
+				Location:      lsp.Location{URI: uri, Range: symbol.SelectionRange},
+			},
+		}
+		score, ok := fuzzyScore(query, symbol.Name)
+		// Clients filter the results by name again, so shapes found by their label
+		// are named after it.
+		if labelScore, labelOK := fuzzyScore(query, symbol.Detail); symbol.Kind != lsp.SymbolKindModule && labelOK && (!ok || labelScore > score) {
+			score, ok = labelScore, true
+			match.Name = symbol.Detail
+			match.ContainerName = id
+		}
+		if ok {
+			match.score = score
+			matches = append(matches, match)
+		}
+		matches = append(matches, matchSymbols(uri, symbol.Children, id, query)...)
+	}
+	return matches
+}
+
+// maxWorkspaceSymbols caps the results of a search, clients search again as the
+// query narrows.
+const maxWorkspaceSymbols = 100
+
+// fileOutline returns the outline of the file at path, cached until it changes.
+// Files are only parsed, compiling every file on each keystroke is too slow for
+// large workspaces, so kinds are less precise than in the document outline.
+func (s *State) fileOutline(path string) []lsp.DocumentSymbol {
+	if symbols, ok := s.outlines[path]; ok {
+		return symbols
+	}
+	var symbols []lsp.DocumentSymbol
+	if ast := s.parseFile(path); ast != nil {
+		symbols = documentSymbols(ast, newOutline(nil))
+	}
+	s.outlines[path] = symbols
+	return symbols
+}
+
+func (s *State) WorkspaceSymbols(id any, query string) lsp.WorkspaceSymbolResponse {
+	response := lsp.WorkspaceSymbolResponse{
+		Response: lsp.NewResponse(id),
+		Result:   []lsp.WorkspaceSymbol{},
+	}
+
+	matches := []workspaceSymbol{}
+	for _, path := range s.knownFiles() {
+		matches = append(matches, matchSymbols(lsp.File(path), s.fileOutline(path), "", query)...)
+	}
+	slices.SortStableFunc(matches, func(a, b workspaceSymbol) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		return cmp.Compare(len(a.Name), len(b.Name))
+	})
+	if len(matches) > maxWorkspaceSymbols {
+		matches = matches[:maxWorkspaceSymbols]
+	}
+
+	for _, match := range matches {
+		response.Result = append(response.Result, match.WorkspaceSymbol)
+	}
+	return response
+}
@@ -0,0 +1,119 @@
+package analysis_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ram02z/d2-language-server/lsp"
+)
+
+func TestWorkspaceSymbols(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"billing.d2":  "payments: {\n  payments-db: Ledger {shape: cylinder}\n  api -> payments-db\n}\n",
+		"overview.d2": "classes: {\n  db: {shape: cylinder}\n}\npdb: Payments Database\nlayers: {\n  payments: {\n    x\n  }\n}\n",
+	})
+	state := newTestState(t)
+	state.AddWorkspaceFolders([]lsp.WorkspaceFolder{{URI: lsp.File(root), Name: "test"}})
+	billing := lsp.File(filepath.Join(root, "billing.d2"))
+	overview := lsp.File(filepath.Join(root, "overview.d2"))
+
+	r := func(line, start, end int) lsp.Range {
+		return lsp.Range{
+			Start: lsp.Position{Line: line, Character: start},
+			End:   lsp.Position{Line: line, Character: end},
+		}
+	}
+	tests := []struct {
+		query string
+		want  []lsp.WorkspaceSymbol
+	}{
+		{
+			query: "payments-db",
+			want: []lsp.WorkspaceSymbol{
+				{Name: "payments-db", Kind: lsp.SymbolKindObject, ContainerName: "payments", Location: lsp.Location{URI: billing, Range: r(1, 2, 13)}},
+			},
+		},
+		{
+			query: "paydb",
+			want: []lsp.WorkspaceSymbol{
+				{Name: "payments-db", Kind: lsp.SymbolKindObject, ContainerName: "payments", Location: lsp.Location{URI: billing, Range: r(1, 2, 13)}},
+				{Name: "Payments Database", Kind: lsp.SymbolKindObject, ContainerName: "pdb", Location: lsp.Location{URI: overview, Range: r(3, 0, 3)}},
+			},
+		},
+		{
+			query: "ledger",
+			want: []lsp.WorkspaceSymbol{
+				{Name: "Ledger", Kind: lsp.SymbolKindObject, ContainerName: "payments.payments-db", Location: lsp.Location{URI: billing, Range: r(1, 2, 13)}},
+			},
+		},
+		{
+			query: "db",
+			want: []lsp.WorkspaceSymbol{
+				{Name: "db", Kind: lsp.SymbolKindClass, ContainerName: "classes", Location: lsp.Location{URI: overview, Range: r(1, 2, 4)}},
+				{Name: "payments-db", Kind: lsp.SymbolKindObject, ContainerName: "payments", Location: lsp.Location{URI: billing, Range: r(1, 2, 13)}},
+				{Name: "pdb", Kind: lsp.SymbolKindObject, Location: lsp.Location{URI: overview, Range: r(3, 0, 3)}},
+			},
+		},
+		{
+			query: "zzz",
+			want:  []lsp.WorkspaceSymbol{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			got := state.WorkspaceSymbols(1, test.query).Result
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("WorkspaceSymbols(%q) mismatch (-want +got):\n%s", test.query, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceSymbolsUpdates(t *testing.T) {
+	shapes := strings.Builder{}
+	for i := range 150 {
+		fmt.Fprintf(&shapes, "shape%d\n", i)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"many.d2": shapes.String(),
+		"edit.d2": "old\n",
+	})
+	state := newTestState(t)
+	state.AddWorkspaceFolders([]lsp.WorkspaceFolder{{URI: lsp.File(root), Name: "test"}})
+
+	if got := state.WorkspaceSymbols(1, "shape").Result; len(got) != 100 {
+		t.Errorf("WorkspaceSymbols() returned %d symbols, want 100", len(got))
+	}
+
+	names := func(query string) []string {
+		names := []string{}
+		for _, symbol := range state.WorkspaceSymbols(1, query).Result {
+			names = append(names, symbol.Name)
+		}
+		return names
+	}
+	if diff := cmp.Diff([]string{"old"}, names("old")); diff != "" {
+		t.Errorf("WorkspaceSymbols() mismatch (-want +got):\n%s", diff)
+	}
+
+	path := filepath.Join(root, "edit.d2")
+	if err := os.WriteFile(path, []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state.UpdateFile(path, lsp.Changed)
+	if diff := cmp.Diff([]string{}, names("old")); diff != "" {
+		t.Errorf("WorkspaceSymbols() after a change mismatch (-want +got):\n%s", diff)
+	}
+
+	uri := lsp.File(path)
+	state.OpenDocument(uri, 1, "new\n")
+	state.UpdateDocument(uri, 2, "newer\n")
+	if diff := cmp.Diff([]string{"newer"}, names("newer")); diff != "" {
+		t.Errorf("WorkspaceSymbols() after an edit mismatch (-want +got):\n%s", diff)
+	}
+}
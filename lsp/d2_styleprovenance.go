@@ -0,0 +1,44 @@
+package lsp
+
+type StyleProvenanceRequest struct {
+	Request
+	Params StyleProvenanceParams `json:"params"`
+}
+
+type StyleProvenanceParams struct {
+	TextDocumentPositionParams
+}
+
+type StyleProvenanceResponse struct {
+	Response
+	Result *StyleProvenanceResult `json:"result"`
+}
+
+type StyleProvenanceResult struct {
+	Object     string                `json:"object"`
+	Attributes []AttributeProvenance `json:"attributes"`
+}
+
+// AttributeProvenance lists the rules setting an attribute, the effective one first.
+type AttributeProvenance struct {
+	Attribute string             `json:"attribute"`
+	Value     string             `json:"value"`
+	Sources   []ProvenanceSource `json:"sources"`
+}
+
+type ProvenanceSource struct {
+	Kind     ProvenanceKind `json:"kind"`
+	Name     string         `json:"name,omitempty"`
+	Value    string         `json:"value"`
+	Location Location       `json:"location"`
+}
+
+type ProvenanceKind string
+
+const (
+	ProvenanceObject ProvenanceKind = "object"
+	ProvenanceGlob   ProvenanceKind = "glob"
+	ProvenanceBoard  ProvenanceKind = "board"
+	ProvenanceImport ProvenanceKind = "import"
+	ProvenanceClass  ProvenanceKind = "class"
+)
@@ -6,8 +6,19 @@ type InitializeRequest struct {
 }
 
 type InitializeRequestParams struct {
-	ClientInfo       *ClientInfo       `json:"clientInfo"`
-	WorkspaceFolders []WorkspaceFolder `json:"workspaceFolders"`
+	ClientInfo            *ClientInfo           `json:"clientInfo"`
+	WorkspaceFolders      []WorkspaceFolder     `json:"workspaceFolders"`
+	InitializationOptions InitializationOptions `json:"initializationOptions"`
+}
+
+// InitializationOptions are the server specific settings sent by the client.
+type InitializationOptions struct {
+	References ReferencesOptions `json:"references"`
+}
+
+type ReferencesOptions struct {
+	// Also list the objects a glob pattern matched.
+	IncludeGlobs bool `json:"includeGlobs"`
 }
 
 type ClientInfo struct {
@@ -26,12 +37,25 @@ type InitializeResult struct {
 }
 
 type ServerCapabilities struct {
-	TextDocumentSync           int               `json:"textDocumentSync"`
-	CompletionProvider         CompletionOptions `json:"completionProvider"`
-	HoverProvider              bool              `json:"hoverProvider"`
-	DefinitionProvider         bool              `json:"definitionProvider"`
-	DocumentFormattingProvider bool              `json:"documentFormattingProvider"`
-	Workspace                  Workspace         `json:"workspace"`
+	TextDocumentSync           int                   `json:"textDocumentSync"`
+	CompletionProvider         CompletionOptions     `json:"completionProvider"`
+	HoverProvider              bool                  `json:"hoverProvider"`
+	DefinitionProvider         bool                  `json:"definitionProvider"`
+	ReferencesProvider         bool                  `json:"referencesProvider"`
+	DocumentHighlightProvider  bool                  `json:"documentHighlightProvider"`
+	DocumentSymbolProvider     bool                  `json:"documentSymbolProvider"`
+	CallHierarchyProvider      bool                  `json:"callHierarchyProvider"`
+	TypeHierarchyProvider      bool                  `json:"typeHierarchyProvider"`
+	RenameProvider             RenameOptions         `json:"renameProvider"`
+	CodeActionProvider         CodeActionOptions     `json:"codeActionProvider"`
+	ExecuteCommandProvider     ExecuteCommandOptions `json:"executeCommandProvider"`
+	DocumentFormattingProvider bool                  `json:"documentFormattingProvider"`
+	FoldingRangeProvider       bool                  `json:"foldingRangeProvider"`
+	SelectionRangeProvider     bool                  `json:"selectionRangeProvider"`
+	SemanticTokensProvider     SemanticTokensOptions `json:"semanticTokensProvider"`
+	InlayHintProvider          bool                  `json:"inlayHintProvider"`
+	WorkspaceSymbolProvider    bool                  `json:"workspaceSymbolProvider"`
+	Workspace                  Workspace             `json:"workspace"`
 }
 
 type CompletionOptions struct {
@@ -49,6 +73,26 @@ type ServerInfo struct {
 	Version string `json:"version"`
 }
 
+// Folders are included since moving or deleting one affects the D2 files inside.
+var d2FileOperations = FileOperationRegistrationOptions{
+	Filters: []FileOperationFilter{
+		{
+			Scheme: "file",
+			Pattern: FileOperationPattern{
+				Glob:    "**/*.d2",
+				Matches: FileOperationPatternKindFile,
+			},
+		},
+		{
+			Scheme: "file",
+			Pattern: FileOperationPattern{
+				Glob:    "**",
+				Matches: FileOperationPatternKindFolder,
+			},
+		},
+	},
+}
+
 func NewInitializeResponse(id any) InitializeResponse {
 	return InitializeResponse{
 		Response: NewResponse(id),
@@ -69,12 +113,57 @@ func NewInitializeResponse(id any) InitializeResponse {
 				},
 				HoverProvider:              true,
 				DefinitionProvider:         true,
+				ReferencesProvider:         true,
+				DocumentHighlightProvider:  true,
+				DocumentSymbolProvider:     true,
+				CallHierarchyProvider:      true,
+				TypeHierarchyProvider:      true,
 				DocumentFormattingProvider: true,
+				FoldingRangeProvider:       true,
+				SelectionRangeProvider:     true,
+				InlayHintProvider:          true,
+				WorkspaceSymbolProvider:    true,
+				RenameProvider: RenameOptions{
+					PrepareProvider: true,
+				},
+				CodeActionProvider: CodeActionOptions{
+					CodeActionKinds: []CodeActionKind{
+						QuickFix,
+						Refactor,
+						RefactorExtract,
+						RefactorInline,
+						RefactorRewrite,
+						RefactorMove,
+					},
+				},
+				SemanticTokensProvider: SemanticTokensOptions{
+					Legend: SemanticTokensLegend{
+						TokenTypes:     SemanticTokenTypes,
+						TokenModifiers: SemanticTokenModifiers,
+					},
+					Range: true,
+					Full: SemanticTokensFullOptions{
+						Delta: true,
+					},
+				},
+				ExecuteCommandProvider: ExecuteCommandOptions{
+					Commands: []string{
+						TrackFileCommand,
+						MoveObjectCommand,
+						DeleteObjectCommand,
+					},
+				},
 				Workspace: Workspace{
 					WorkspaceFolders: WorkspaceFoldersServerCapabilities{
 						Supported:           true,
 						ChangeNotifications: true,
 					},
+					FileOperations: FileOperationsServerCapabilities{
+						DidCreate:  &d2FileOperations,
+						DidRename:  &d2FileOperations,
+						DidDelete:  &d2FileOperations,
+						WillRename: &d2FileOperations,
+					},
 				},
 			},
 			ServerInfo: ServerInfo{
@@ -10,9 +10,35 @@ const (
 	PublishDiagnostics        Method = "textDocument/publishDiagnostics"
 	Hover                     Method = "textDocument/hover"
 	Definition                Method = "textDocument/definition"
+	References                Method = "textDocument/references"
+	DocumentHighlights        Method = "textDocument/documentHighlight"
+	DocumentSymbols           Method = "textDocument/documentSymbol"
+	PrepareRename             Method = "textDocument/prepareRename"
+	Rename                    Method = "textDocument/rename"
 	Completion                Method = "textDocument/completion"
+	CodeActions               Method = "textDocument/codeAction"
+	PrepareCallHierarchy      Method = "textDocument/prepareCallHierarchy"
+	IncomingCalls             Method = "callHierarchy/incomingCalls"
+	OutgoingCalls             Method = "callHierarchy/outgoingCalls"
+	PrepareTypeHierarchy      Method = "textDocument/prepareTypeHierarchy"
+	Supertypes                Method = "typeHierarchy/supertypes"
+	Subtypes                  Method = "typeHierarchy/subtypes"
 	Formatting                Method = "textDocument/formatting"
+	FoldingRanges             Method = "textDocument/foldingRange"
+	SelectionRanges           Method = "textDocument/selectionRange"
+	SemanticTokensFull        Method = "textDocument/semanticTokens/full"
+	SemanticTokensFullDelta   Method = "textDocument/semanticTokens/full/delta"
+	SemanticTokensRange       Method = "textDocument/semanticTokens/range"
+	InlayHints                Method = "textDocument/inlayHint"
 	DidChangeWorkspaceFolders Method = "workspace/didChangeWorkspaceFolders"
 	DidChangeWatchedFiles     Method = "workspace/didChangeWatchedFiles"
+	WillRenameFiles           Method = "workspace/willRenameFiles"
+	DidRenameFiles            Method = "workspace/didRenameFiles"
+	DidCreateFiles            Method = "workspace/didCreateFiles"
+	DidDeleteFiles            Method = "workspace/didDeleteFiles"
+	ExecuteCommand            Method = "workspace/executeCommand"
+	WorkspaceSymbols          Method = "workspace/symbol"
+	ApplyEdit                 Method = "workspace/applyEdit"
 	ClientRegisterCapability  Method = "client/registerCapability"
+	StyleProvenance           Method = "d2/styleProvenance"
 )
@@ -0,0 +1,70 @@
+package lsp
+
+type CallHierarchyPrepareRequest struct {
+	Request
+	Params CallHierarchyPrepareParams `json:"params"`
+}
+
+type CallHierarchyPrepareParams struct {
+	TextDocumentPositionParams
+}
+
+type CallHierarchyPrepareResponse struct {
+	Response
+	Result []CallHierarchyItem `json:"result"`
+}
+
+type CallHierarchyItem struct {
+	Name           string      `json:"name"`
+	Kind           SymbolKind  `json:"kind"`
+	Detail         string      `json:"detail,omitempty"`
+	URI            DocumentURI `json:"uri"`
+	Range          Range       `json:"range"`
+	SelectionRange Range       `json:"selectionRange"`
+	Data           SymbolData  `json:"data"`
+}
+
+// SymbolData identifies the object an item was created for, so that it can be
+// found again when the client sends the item back.
+type SymbolData struct {
+	URI DocumentURI `json:"uri"`
+	ID  string      `json:"id"`
+}
+
+type CallHierarchyIncomingCallsRequest struct {
+	Request
+	Params CallHierarchyIncomingCallsParams `json:"params"`
+}
+
+type CallHierarchyIncomingCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+type CallHierarchyIncomingCallsResponse struct {
+	Response
+	Result []CallHierarchyIncomingCall `json:"result"`
+}
+
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+type CallHierarchyOutgoingCallsRequest struct {
+	Request
+	Params CallHierarchyOutgoingCallsParams `json:"params"`
+}
+
+type CallHierarchyOutgoingCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+type CallHierarchyOutgoingCallsResponse struct {
+	Response
+	Result []CallHierarchyOutgoingCall `json:"result"`
+}
+
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"`
+}
@@ -0,0 +1,54 @@
+package lsp
+
+type CodeActionRequest struct {
+	Request
+	Params CodeActionParams `json:"params"`
+}
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	// Limits the kinds of actions returned, every kind is returned when empty.
+	Only []CodeActionKind `json:"only,omitempty"`
+}
+
+type CodeActionResponse struct {
+	Response
+	Result []CodeAction `json:"result"`
+}
+
+type CodeAction struct {
+	Title       string         `json:"title"`
+	Kind        CodeActionKind `json:"kind,omitempty"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	IsPreferred bool           `json:"isPreferred,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit,omitempty"`
+	// Executed after Edit is applied.
+	Command *Command `json:"command,omitempty"`
+}
+
+type Command struct {
+	Title     string `json:"title"`
+	Command   string `json:"command"`
+	Arguments []any  `json:"arguments,omitempty"`
+}
+
+type CodeActionKind string
+
+const (
+	QuickFix        CodeActionKind = "quickfix"
+	Refactor        CodeActionKind = "refactor"
+	RefactorExtract CodeActionKind = "refactor.extract"
+	RefactorInline  CodeActionKind = "refactor.inline"
+	RefactorRewrite CodeActionKind = "refactor.rewrite"
+	RefactorMove    CodeActionKind = "refactor.move"
+)
+
+type CodeActionOptions struct {
+	CodeActionKinds []CodeActionKind `json:"codeActionKinds"`
+}
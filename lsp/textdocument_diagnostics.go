@@ -15,6 +15,8 @@ type Diagnostic struct {
 	Message  string             `json:"message"`
 	Range    Range              `json:"range"`
 	Severity DiagnosticSeverity `json:"severity"`
+	// Identifies the kind of problem, code actions use it to find a fix.
+	Code string `json:"code,omitempty"`
 }
 
 type DiagnosticSeverity int
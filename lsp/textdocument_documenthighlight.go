@@ -0,0 +1,28 @@
+package lsp
+
+type DocumentHighlightRequest struct {
+	Request
+	Params DocumentHighlightParams `json:"params"`
+}
+
+type DocumentHighlightParams struct {
+	TextDocumentPositionParams
+}
+
+type DocumentHighlightResponse struct {
+	Response
+	Result []DocumentHighlight `json:"result"`
+}
+
+type DocumentHighlight struct {
+	Range Range                 `json:"range"`
+	Kind  DocumentHighlightKind `json:"kind"`
+}
+
+type DocumentHighlightKind int
+
+const (
+	DocumentHighlightKindText  DocumentHighlightKind = 1
+	DocumentHighlightKindRead  DocumentHighlightKind = 2
+	DocumentHighlightKindWrite DocumentHighlightKind = 3
+)
@@ -0,0 +1,28 @@
+package lsp
+
+type FoldingRangeRequest struct {
+	Request
+	Params FoldingRangeParams `json:"params"`
+}
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type FoldingRangeResponse struct {
+	Response
+	Result []FoldingRange `json:"result"`
+}
+
+type FoldingRange struct {
+	StartLine int              `json:"startLine"`
+	EndLine   int              `json:"endLine"`
+	Kind      FoldingRangeKind `json:"kind,omitempty"`
+}
+
+type FoldingRangeKind string
+
+const (
+	FoldingRangeKindComment FoldingRangeKind = "comment"
+	FoldingRangeKindRegion  FoldingRangeKind = "region"
+)
@@ -11,9 +11,9 @@ type HoverParams struct {
 
 type HoverResponse struct {
 	Response
-	Result HoverResult `json:"result"`
+	Result *HoverResult `json:"result"`
 }
 
 type HoverResult struct {
-	Contents string `json:"contents"`
+	Contents MarkupContent `json:"contents"`
 }
@@ -0,0 +1,40 @@
+package lsp
+
+type PrepareRenameRequest struct {
+	Request
+	Params PrepareRenameParams `json:"params"`
+}
+
+type PrepareRenameParams struct {
+	TextDocumentPositionParams
+}
+
+type PrepareRenameResponse struct {
+	Response
+	Result *PrepareRenameResult `json:"result"`
+}
+
+type PrepareRenameResult struct {
+	Range       Range  `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
+type RenameRequest struct {
+	Request
+	Params RenameParams `json:"params"`
+}
+
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+type RenameResponse struct {
+	Response
+	// Omitted when the rename is refused with an error.
+	Result *WorkspaceEdit `json:"result,omitempty"`
+}
+
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider"`
+}
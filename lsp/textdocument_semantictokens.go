@@ -0,0 +1,114 @@
+package lsp
+
+type SemanticTokenType int
+
+// Token types, in the order of SemanticTokenTypes.
+const (
+	SemanticTokenBoard SemanticTokenType = iota
+	SemanticTokenShape
+	SemanticTokenClass
+	SemanticTokenVariable
+	SemanticTokenAttribute
+	SemanticTokenKeyword
+	SemanticTokenOperator
+	SemanticTokenLabel
+	SemanticTokenImport
+)
+
+// SemanticTokenTypes maps the token types D2 uses to the standard ones clients know
+// how to color.
+var SemanticTokenTypes = []string{
+	"namespace",
+	"type",
+	"class",
+	"variable",
+	"property",
+	"keyword",
+	"operator",
+	"string",
+	"decorator",
+}
+
+// Token modifiers are bit flags, in the order of SemanticTokenModifiers. Tokens
+// without the declaration modifier are references.
+const (
+	SemanticTokenDeclaration = 1 << iota
+)
+
+var SemanticTokenModifiers = []string{
+	"declaration",
+}
+
+type SemanticTokensRequest struct {
+	Request
+	Params SemanticTokensParams `json:"params"`
+}
+
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type SemanticTokensRangeRequest struct {
+	Request
+	Params SemanticTokensRangeParams `json:"params"`
+}
+
+type SemanticTokensRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+type SemanticTokensDeltaRequest struct {
+	Request
+	Params SemanticTokensDeltaParams `json:"params"`
+}
+
+type SemanticTokensDeltaParams struct {
+	TextDocument     TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                 `json:"previousResultId"`
+}
+
+type SemanticTokensResponse struct {
+	Response
+	Result SemanticTokens `json:"result"`
+}
+
+// SemanticTokensDeltaResponse holds either SemanticTokens, when the previous result
+// is unknown, or SemanticTokensDelta.
+type SemanticTokensDeltaResponse struct {
+	Response
+	Result any `json:"result"`
+}
+
+type SemanticTokens struct {
+	ResultID string `json:"resultId,omitempty"`
+	// Five integers per token: the line and start relative to the previous token,
+	// the length, the type and the modifiers.
+	Data []int `json:"data"`
+}
+
+type SemanticTokensDelta struct {
+	ResultID string               `json:"resultId,omitempty"`
+	Edits    []SemanticTokensEdit `json:"edits"`
+}
+
+type SemanticTokensEdit struct {
+	Start       int   `json:"start"`
+	DeleteCount int   `json:"deleteCount"`
+	Data        []int `json:"data,omitempty"`
+}
+
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend      `json:"legend"`
+	Range  bool                      `json:"range"`
+	Full   SemanticTokensFullOptions `json:"full"`
+}
+
+type SemanticTokensFullOptions struct {
+	Delta bool `json:"delta"`
+}
@@ -0,0 +1,50 @@
+package lsp
+
+type TypeHierarchyPrepareRequest struct {
+	Request
+	Params TypeHierarchyPrepareParams `json:"params"`
+}
+
+type TypeHierarchyPrepareParams struct {
+	TextDocumentPositionParams
+}
+
+type TypeHierarchyPrepareResponse struct {
+	Response
+	Result []TypeHierarchyItem `json:"result"`
+}
+
+// TypeHierarchyItem has the same fields as CallHierarchyItem so that one can be
+// converted into the other.
+type TypeHierarchyItem struct {
+	Name           string      `json:"name"`
+	Kind           SymbolKind  `json:"kind"`
+	Detail         string      `json:"detail,omitempty"`
+	URI            DocumentURI `json:"uri"`
+	Range          Range       `json:"range"`
+	SelectionRange Range       `json:"selectionRange"`
+	Data           SymbolData  `json:"data"`
+}
+
+type TypeHierarchySupertypesRequest struct {
+	Request
+	Params TypeHierarchySupertypesParams `json:"params"`
+}
+
+type TypeHierarchySupertypesParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+type TypeHierarchySubtypesRequest struct {
+	Request
+	Params TypeHierarchySubtypesParams `json:"params"`
+}
+
+type TypeHierarchySubtypesParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+type TypeHierarchyResponse struct {
+	Response
+	Result []TypeHierarchyItem `json:"result"`
+}
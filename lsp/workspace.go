@@ -1,8 +1,8 @@
 package lsp
 
-// TODO: support file operations
 type Workspace struct {
 	WorkspaceFolders WorkspaceFoldersServerCapabilities `json:"workspaceFolders"`
+	FileOperations   FileOperationsServerCapabilities   `json:"fileOperations"`
 }
 
 type WorkspaceFoldersServerCapabilities struct {
@@ -10,6 +10,34 @@ type WorkspaceFoldersServerCapabilities struct {
 	ChangeNotifications bool `json:"changeNotifications"`
 }
 
+type FileOperationsServerCapabilities struct {
+	DidCreate  *FileOperationRegistrationOptions `json:"didCreate,omitempty"`
+	DidRename  *FileOperationRegistrationOptions `json:"didRename,omitempty"`
+	DidDelete  *FileOperationRegistrationOptions `json:"didDelete,omitempty"`
+	WillRename *FileOperationRegistrationOptions `json:"willRename,omitempty"`
+}
+
+type FileOperationRegistrationOptions struct {
+	Filters []FileOperationFilter `json:"filters"`
+}
+
+type FileOperationFilter struct {
+	Scheme  string               `json:"scheme,omitempty"`
+	Pattern FileOperationPattern `json:"pattern"`
+}
+
+type FileOperationPattern struct {
+	Glob    string                   `json:"glob"`
+	Matches FileOperationPatternKind `json:"matches,omitempty"`
+}
+
+type FileOperationPatternKind string
+
+const (
+	FileOperationPatternKindFile   FileOperationPatternKind = "file"
+	FileOperationPatternKindFolder FileOperationPatternKind = "folder"
+)
+
 type WorkspaceFolder struct {
 	URI  URI `json:"uri"`
 	Name string `json:"name"`
@@ -50,3 +78,53 @@ const (
 	Changed FileChangeType = 2
 	Deleted FileChangeType = 3
 )
+
+type WillRenameFilesRequest struct {
+	Request
+	Params RenameFilesParams `json:"params"`
+}
+
+type WillRenameFilesResponse struct {
+	Response
+	Result *WorkspaceEdit `json:"result"`
+}
+
+type DidRenameFilesNotification struct {
+	Notification
+	Params RenameFilesParams `json:"params"`
+}
+
+type RenameFilesParams struct {
+	Files []FileRename `json:"files"`
+}
+
+type FileRename struct {
+	OldURI DocumentURI `json:"oldUri"`
+	NewURI DocumentURI `json:"newUri"`
+}
+
+type DidCreateFilesNotification struct {
+	Notification
+	Params CreateFilesParams `json:"params"`
+}
+
+type CreateFilesParams struct {
+	Files []FileCreate `json:"files"`
+}
+
+type FileCreate struct {
+	URI DocumentURI `json:"uri"`
+}
+
+type DidDeleteFilesNotification struct {
+	Notification
+	Params DeleteFilesParams `json:"params"`
+}
+
+type DeleteFilesParams struct {
+	Files []FileDelete `json:"files"`
+}
+
+type FileDelete struct {
+	URI DocumentURI `json:"uri"`
+}
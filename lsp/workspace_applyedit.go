@@ -0,0 +1,7 @@
+package lsp
+
+type ApplyWorkspaceEditParams struct {
+	// Shown by clients that keep an undo stack.
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
@@ -0,0 +1,33 @@
+package lsp
+
+import "encoding/json"
+
+const (
+	// TrackFileCommand adds a file created by a code action to its workspace folder.
+	TrackFileCommand = "d2.trackFile"
+	// MoveObjectCommand moves the object with the key given as second argument to the
+	// key given as third in the document given as first, updating its references.
+	MoveObjectCommand = "d2.moveObject"
+	// DeleteObjectCommand deletes the object with the key given as second argument,
+	// along with its connections, from the document given as first.
+	DeleteObjectCommand = "d2.deleteObject"
+)
+
+type ExecuteCommandRequest struct {
+	Request
+	Params ExecuteCommandParams `json:"params"`
+}
+
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments"`
+}
+
+type ExecuteCommandResponse struct {
+	Response
+	Result any `json:"result"`
+}
+
+type ExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
+}
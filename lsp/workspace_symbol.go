@@ -0,0 +1,22 @@
+package lsp
+
+type WorkspaceSymbolRequest struct {
+	Request
+	Params WorkspaceSymbolParams `json:"params"`
+}
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+type WorkspaceSymbolResponse struct {
+	Response
+	Result []WorkspaceSymbol `json:"result"`
+}
+
+type WorkspaceSymbol struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	ContainerName string     `json:"containerName,omitempty"`
+	Location      Location   `json:"location"`
+}
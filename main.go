@@ -22,10 +22,35 @@ var handlers = map[lsp.Method]HandlerFunc{
 	lsp.DidCloseTextDocument:      handleDidCloseTextDocument,
 	lsp.Hover:                     handleHover,
 	lsp.Definition:                handleDefinition,
+	lsp.References:                handleReferences,
+	lsp.DocumentHighlights:        handleDocumentHighlight,
+	lsp.DocumentSymbols:           handleDocumentSymbol,
+	lsp.PrepareRename:             handlePrepareRename,
+	lsp.Rename:                    handleRename,
 	lsp.Completion:                handleCompletion,
+	lsp.CodeActions:               handleCodeAction,
+	lsp.PrepareCallHierarchy:      handlePrepareCallHierarchy,
+	lsp.IncomingCalls:             handleIncomingCalls,
+	lsp.OutgoingCalls:             handleOutgoingCalls,
+	lsp.PrepareTypeHierarchy:      handlePrepareTypeHierarchy,
+	lsp.Supertypes:                handleSupertypes,
+	lsp.Subtypes:                  handleSubtypes,
 	lsp.Formatting:                handleFormatting,
+	lsp.FoldingRanges:             handleFoldingRange,
+	lsp.SelectionRanges:           handleSelectionRange,
+	lsp.SemanticTokensFull:        handleSemanticTokensFull,
+	lsp.SemanticTokensFullDelta:   handleSemanticTokensFullDelta,
+	lsp.SemanticTokensRange:       handleSemanticTokensRange,
+	lsp.InlayHints:                handleInlayHint,
+	lsp.StyleProvenance:           handleStyleProvenance,
 	lsp.DidChangeWorkspaceFolders: handleDidChangeWorkspaceFolders,
 	lsp.DidChangeWatchedFiles:     handleDidChangeWatchedFiles,
+	lsp.WillRenameFiles:           handleWillRenameFiles,
+	lsp.DidRenameFiles:            handleDidRenameFiles,
+	lsp.DidCreateFiles:            handleDidCreateFiles,
+	lsp.DidDeleteFiles:            handleDidDeleteFiles,
+	lsp.ExecuteCommand:            handleExecuteCommand,
+	lsp.WorkspaceSymbols:          handleWorkspaceSymbol,
 }
 
 func handleMessage(
@@ -58,6 +83,7 @@ func handleInitialize(logger *log.Logger, writer io.Writer, state analysis.State
 		request.Params.ClientInfo.Version,
 	)
 
+	state.SetInitializationOptions(request.Params.InitializationOptions)
 	if folders := request.Params.WorkspaceFolders; folders != nil {
 		state.AddWorkspaceFolders(folders)
 	}
@@ -98,7 +124,11 @@ func handleDidOpenTextDocument(logger *log.Logger, writer io.Writer, state analy
 	}
 
 	logger.Printf("opened document: %s", request.Params.TextDocument.URI)
-	diagnostics := state.OpenDocument(request.Params.TextDocument.URI, request.Params.TextDocument.Text)
+	diagnostics := state.OpenDocument(
+		request.Params.TextDocument.URI,
+		request.Params.TextDocument.Version,
+		request.Params.TextDocument.Text,
+	)
 	writeResponse(writer, lsp.PublishDiagnosticsNotification{
 		Notification: lsp.NewNotification(lsp.PublishDiagnostics),
 		Params: lsp.PublishDiagnosticsParams{
@@ -122,7 +152,11 @@ func handleDidChangeTextDocument(logger *log.Logger, writer io.Writer, state ana
 	// HACK: only considering the final change
 	if contentChangesLen > 0 {
 		lastChangeEvent := request.Params.ContentChanges[contentChangesLen-1]
-		diagnostics = append(diagnostics, state.UpdateDocument(request.Params.TextDocument.URI, lastChangeEvent.Text)...)
+		diagnostics = append(diagnostics, state.UpdateDocument(
+			request.Params.TextDocument.URI,
+			request.Params.TextDocument.Version,
+			lastChangeEvent.Text,
+		)...)
 	}
 	writeResponse(writer, lsp.PublishDiagnosticsNotification{
 		Notification: lsp.NewNotification(lsp.PublishDiagnostics),
@@ -167,6 +201,137 @@ func handleDefinition(logger *log.Logger, writer io.Writer, state analysis.State
 	writeResponse(writer, msg)
 }
 
+func handleReferences(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.ReferencesRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.References, err)
+		return
+	}
+
+	msg := state.References(
+		request.ID,
+		request.Params.TextDocument.URI,
+		request.Params.Position,
+		request.Params.Context.IncludeDeclaration,
+	)
+	writeResponse(writer, msg)
+}
+
+func handleDocumentHighlight(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.DocumentHighlightRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.DocumentHighlights, err)
+		return
+	}
+
+	msg := state.DocumentHighlight(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	writeResponse(writer, msg)
+}
+
+func handleDocumentSymbol(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.DocumentSymbolRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.DocumentSymbols, err)
+		return
+	}
+
+	msg := state.DocumentSymbols(request.ID, request.Params.TextDocument.URI)
+	writeResponse(writer, msg)
+}
+
+func handlePrepareCallHierarchy(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.CallHierarchyPrepareRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.PrepareCallHierarchy, err)
+		return
+	}
+
+	msg := state.PrepareCallHierarchy(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	writeResponse(writer, msg)
+}
+
+func handleIncomingCalls(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.CallHierarchyIncomingCallsRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.IncomingCalls, err)
+		return
+	}
+
+	msg := state.IncomingCalls(request.ID, request.Params.Item)
+	writeResponse(writer, msg)
+}
+
+func handleOutgoingCalls(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.CallHierarchyOutgoingCallsRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.OutgoingCalls, err)
+		return
+	}
+
+	msg := state.OutgoingCalls(request.ID, request.Params.Item)
+	writeResponse(writer, msg)
+}
+
+func handlePrepareTypeHierarchy(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.TypeHierarchyPrepareRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.PrepareTypeHierarchy, err)
+		return
+	}
+
+	msg := state.PrepareTypeHierarchy(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	writeResponse(writer, msg)
+}
+
+func handleSupertypes(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.TypeHierarchySupertypesRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.Supertypes, err)
+		return
+	}
+
+	msg := state.Supertypes(request.ID, request.Params.Item)
+	writeResponse(writer, msg)
+}
+
+func handleSubtypes(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.TypeHierarchySubtypesRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.Subtypes, err)
+		return
+	}
+
+	msg := state.Subtypes(request.ID, request.Params.Item)
+	writeResponse(writer, msg)
+}
+
+func handlePrepareRename(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.PrepareRenameRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.PrepareRename, err)
+		return
+	}
+
+	msg := state.PrepareRename(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	writeResponse(writer, msg)
+}
+
+func handleRename(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.RenameRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.Rename, err)
+		return
+	}
+
+	msg := state.Rename(
+		request.ID,
+		request.Params.TextDocument.URI,
+		request.Params.Position,
+		request.Params.NewName,
+	)
+	writeResponse(writer, msg)
+}
+
 func handleCompletion(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
 	var request lsp.CompletionRequest
 	if err := json.Unmarshal(contents, &request); err != nil {
@@ -188,6 +353,22 @@ func handleCompletion(logger *log.Logger, writer io.Writer, state analysis.State
 	writeResponse(writer, msg)
 }
 
+func handleCodeAction(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.CodeActionRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.CodeActions, err)
+		return
+	}
+
+	msg := state.CodeActions(
+		request.ID,
+		request.Params.TextDocument.URI,
+		request.Params.Range,
+		request.Params.Context,
+	)
+	writeResponse(writer, msg)
+}
+
 func handleFormatting(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
 	var request lsp.FormattingRequest
 	if err := json.Unmarshal(contents, &request); err != nil {
@@ -200,6 +381,83 @@ func handleFormatting(logger *log.Logger, writer io.Writer, state analysis.State
 	logger.Printf("formatted: %s", request.Params.TextDocument.URI)
 }
 
+func handleFoldingRange(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.FoldingRangeRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.FoldingRanges, err)
+		return
+	}
+
+	msg := state.FoldingRanges(request.ID, request.Params.TextDocument.URI)
+	writeResponse(writer, msg)
+}
+
+func handleSelectionRange(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.SelectionRangeRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.SelectionRanges, err)
+		return
+	}
+
+	msg := state.SelectionRanges(request.ID, request.Params.TextDocument.URI, request.Params.Positions)
+	writeResponse(writer, msg)
+}
+
+func handleSemanticTokensFull(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.SemanticTokensRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.SemanticTokensFull, err)
+		return
+	}
+
+	msg := state.SemanticTokens(request.ID, request.Params.TextDocument.URI)
+	writeResponse(writer, msg)
+}
+
+func handleSemanticTokensFullDelta(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.SemanticTokensDeltaRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.SemanticTokensFullDelta, err)
+		return
+	}
+
+	msg := state.SemanticTokensDelta(request.ID, request.Params.TextDocument.URI, request.Params.PreviousResultID)
+	writeResponse(writer, msg)
+}
+
+func handleSemanticTokensRange(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.SemanticTokensRangeRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.SemanticTokensRange, err)
+		return
+	}
+
+	msg := state.SemanticTokensRange(request.ID, request.Params.TextDocument.URI, request.Params.Range)
+	writeResponse(writer, msg)
+}
+
+func handleInlayHint(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.InlayHintRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.InlayHints, err)
+		return
+	}
+
+	msg := state.InlayHints(request.ID, request.Params.TextDocument.URI, request.Params.Range)
+	writeResponse(writer, msg)
+}
+
+func handleStyleProvenance(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.StyleProvenanceRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.StyleProvenance, err)
+		return
+	}
+
+	msg := state.StyleProvenance(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	writeResponse(writer, msg)
+}
+
 func handleDidChangeWorkspaceFolders(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
 	var request lsp.DidChangeWorkspaceFoldersNotifications
 	if err := json.Unmarshal(contents, &request); err != nil {
@@ -229,6 +487,74 @@ func handleDidChangeWatchedFiles(logger *log.Logger, writer io.Writer, state ana
 	}
 }
 
+func handleWillRenameFiles(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.WillRenameFilesRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.WillRenameFiles, err)
+		return
+	}
+
+	msg := state.WillRenameFiles(request.ID, request.Params.Files)
+	writeResponse(writer, msg)
+}
+
+func handleDidRenameFiles(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.DidRenameFilesNotification
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.DidRenameFiles, err)
+		return
+	}
+
+	state.RenameFiles(request.Params.Files)
+}
+
+func handleDidCreateFiles(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.DidCreateFilesNotification
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.DidCreateFiles, err)
+		return
+	}
+
+	state.CreateFiles(request.Params.Files)
+}
+
+func handleDidDeleteFiles(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.DidDeleteFilesNotification
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.DidDeleteFiles, err)
+		return
+	}
+
+	state.DeleteFiles(request.Params.Files)
+}
+
+func handleExecuteCommand(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.ExecuteCommandRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.ExecuteCommand, err)
+		return
+	}
+
+	msg, apply := state.ExecuteCommand(request.ID, request.Params.Command, request.Params.Arguments)
+	if apply != nil {
+		if err := writeResponse(writer, lsp.NewRequestWithParams(lsp.ApplyEdit, *apply)); err != nil {
+			logger.Printf("could not apply edit: %s", err)
+		}
+	}
+	writeResponse(writer, msg)
+}
+
+func handleWorkspaceSymbol(logger *log.Logger, writer io.Writer, state analysis.State, contents []byte) {
+	var request lsp.WorkspaceSymbolRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		logger.Printf("error parsing %s request: %s", lsp.WorkspaceSymbols, err)
+		return
+	}
+
+	msg := state.WorkspaceSymbols(request.ID, request.Params.Query)
+	writeResponse(writer, msg)
+}
+
 func writeResponse(writer io.Writer, msg any) error {
 	reply, err := rpc.EncodeMessage(msg)
 	if err != nil {